@@ -3,11 +3,11 @@ package client
 import (
 	"bytes"
 	"context"
-	"crypto/rsa"
 	"encoding/json"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
 	"strings"
 	"time"
@@ -21,6 +21,7 @@ import (
 const (
 	idTokenCertURL        = "https://www.googleapis.com/robot/v1/metadata/x509/securetoken@system.gserviceaccount.com"
 	signInWithPasswordURL = "https://identitytoolkit.googleapis.com/v1/accounts:signInWithPassword"
+	secureTokenURL        = "https://securetoken.googleapis.com/v1/token"
 )
 
 type SignInWithPasswordRequest struct {
@@ -45,16 +46,25 @@ type SignInWithPasswordResponse struct {
 	LocalId      string `json:"localId"`
 }
 
+type RefreshTokenResponse struct {
+	IdToken      string `json:"id_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    string `json:"expires_in"`
+	UserId       string `json:"user_id"`
+}
+
 type FirebaseClient struct {
 	projectID          string
-	publicKeys         map[string]*rsa.PublicKey
+	webApiKey          string
+	publicKeys         *publicKeyCache
 	serviceAccountFile string
 	tokenSource        oauth2.TokenSource
 	logger             *slog.Logger
 }
 
-// Firebase REST client for authenticating email login and verifying JWT token
-func NewFirebaseClient(serviceAccountFile string, logger *slog.Logger) (*FirebaseClient, error) {
+// Firebase REST client for authenticating email login and verifying JWT token.
+// The optional web API key is sent along with the service account access token.
+func NewFirebaseClient(serviceAccountFile, webApiKey string, logger *slog.Logger) (*FirebaseClient, error) {
 	ctx := context.Background()
 
 	serviceAccountJSON, err := os.ReadFile(serviceAccountFile)
@@ -67,9 +77,14 @@ func NewFirebaseClient(serviceAccountFile string, logger *slog.Logger) (*Firebas
 		return nil, fmt.Errorf("error loading service account file: %v", err)
 	}
 
+	publicKeys := newPublicKeyCache(idTokenCertURL, logger)
+	go publicKeys.refreshInBackground()
+
 	return &FirebaseClient{
 		serviceAccountFile: serviceAccountFile,
 		projectID:          creds.ProjectID,
+		webApiKey:          webApiKey,
+		publicKeys:         publicKeys,
 		logger:             logger,
 		tokenSource:        creds.TokenSource,
 	}, nil
@@ -95,7 +110,7 @@ func (c *FirebaseClient) SignInWithEmailAndPassword(req SignInWithPasswordReques
 	}
 
 	client := &http.Client{}
-	request, err := http.NewRequest("POST", signInWithPasswordURL, bytes.NewBuffer(jsonData))
+	request, err := http.NewRequest("POST", c.withApiKey(signInWithPasswordURL), bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, err
 	}
@@ -118,39 +133,51 @@ func (c *FirebaseClient) SignInWithEmailAndPassword(req SignInWithPasswordReques
 	return &res, nil
 }
 
-// Cache or move it outside.
-// Unable to use Firebase admin SDK due to private Cache-Control response header without a max-age value.
-func (c *FirebaseClient) fetchPublicKeys() error {
-	resp, err := http.Get(idTokenCertURL)
+// Exchanges a refresh token for a new ID token using the Secure Token API
+func (c *FirebaseClient) RefreshIdToken(refreshToken string) (*RefreshTokenResponse, error) {
+	form := url.Values{}
+	form.Set("grant_type", "refresh_token")
+	form.Set("refresh_token", refreshToken)
+
+	accessToken, err := c.getAccessToken()
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	request, err := http.NewRequest("POST", c.withApiKey(secureTokenURL), strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Authorization", "Bearer "+accessToken)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := client.Do(request)
 	if err != nil {
-		return fmt.Errorf("error fetching public keys: %v", err)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("error fetching public keys: status %d", resp.StatusCode)
+		return nil, fmt.Errorf("failed to refresh token, status code %d", resp.StatusCode)
 	}
 
-	keys := make(map[string]string)
-	err = json.NewDecoder(resp.Body).Decode(&keys)
-	if err != nil {
-		return fmt.Errorf("error decoding public keys: %v", err)
+	var res RefreshTokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return nil, fmt.Errorf("error decoding refresh token response: %v", err)
 	}
 
-	c.publicKeys = make(map[string]*rsa.PublicKey)
-	for kid, keyStr := range keys {
-		key, err := jwt.ParseRSAPublicKeyFromPEM([]byte(keyStr))
-		if err != nil {
-			return fmt.Errorf("error parsing public key: %v", err)
-		}
-		c.publicKeys[kid] = key
+	return &res, nil
+}
+
+func (c *FirebaseClient) withApiKey(endpoint string) string {
+	if c.webApiKey == "" {
+		return endpoint
 	}
-	return nil
+	return endpoint + "?key=" + url.QueryEscape(c.webApiKey)
 }
 
 func (c *FirebaseClient) VerifyIDToken(idToken string) (*jwt.Token, error) {
-	c.fetchPublicKeys()
-
 	parts := strings.Split(idToken, ".")
 	if len(parts) != 3 {
 		return nil, fmt.Errorf("invalid token")
@@ -169,9 +196,9 @@ func (c *FirebaseClient) VerifyIDToken(idToken string) (*jwt.Token, error) {
 		return nil, fmt.Errorf("error unmarshaling header: %v", err)
 	}
 
-	key, ok := c.publicKeys[header.Kid]
-	if !ok {
-		return nil, fmt.Errorf("public key not found")
+	key, err := c.publicKeys.get(header.Kid)
+	if err != nil {
+		return nil, err
 	}
 
 	token, err := jwt.Parse(idToken, func(token *jwt.Token) (interface{}, error) {
@@ -0,0 +1,178 @@
+package client
+
+import (
+	"crypto/rsa"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+const (
+	defaultKeysMaxAge = time.Hour
+	// how long before expiry the background refresh kicks in
+	keysRefreshAhead = 5 * time.Minute
+	keysRetryDelay   = 30 * time.Second
+	// unknown key ids trigger at most one fetch per interval
+	keysMinFetchInterval = time.Minute
+)
+
+// Exposed on /debug/vars
+var keyCacheMetrics = expvar.NewMap("firebase_public_keys")
+
+// Concurrency-safe cache of the Firebase token signing keys, honouring the
+// expiry announced by the cert endpoint
+type publicKeyCache struct {
+	certURL    string
+	httpClient *http.Client
+	logger     *slog.Logger
+
+	mu          sync.RWMutex
+	keys        map[string]*rsa.PublicKey
+	expiresAt   time.Time
+	lastFetchAt time.Time
+
+	// serializes fetches so concurrent misses hit the endpoint once
+	fetchMu sync.Mutex
+}
+
+func newPublicKeyCache(certURL string, logger *slog.Logger) *publicKeyCache {
+	return &publicKeyCache{
+		certURL:    certURL,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		logger:     logger,
+	}
+}
+
+// Returns the key for kid, fetching the keys when they are expired or
+// when kid is unknown, which happens right after Google rotates them
+func (c *publicKeyCache) get(kid string) (*rsa.PublicKey, error) {
+	key, fresh := c.lookup(kid)
+	if key != nil && fresh {
+		keyCacheMetrics.Add("hits", 1)
+		return key, nil
+	}
+	keyCacheMetrics.Add("misses", 1)
+
+	if err := c.fetch(); err != nil {
+		// an expired key is still better than failing every request
+		if key != nil {
+			return key, nil
+		}
+		return nil, err
+	}
+
+	if key, _ = c.lookup(kid); key == nil {
+		return nil, fmt.Errorf("public key not found")
+	}
+	return key, nil
+}
+
+func (c *publicKeyCache) lookup(kid string) (*rsa.PublicKey, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.keys[kid], time.Now().Before(c.expiresAt)
+}
+
+// Fetches the keys unless they were fetched moments ago: unknown key ids
+// trigger at most one fetch per keysMinFetchInterval and failed fetches of
+// expired keys are retried after keysRetryDelay.
+func (c *publicKeyCache) fetch() error {
+	c.fetchMu.Lock()
+	defer c.fetchMu.Unlock()
+
+	c.mu.RLock()
+	sinceLastFetch := time.Since(c.lastFetchAt)
+	expired := !time.Now().Before(c.expiresAt)
+	c.mu.RUnlock()
+	if (expired && sinceLastFetch < keysRetryDelay) || (!expired && sinceLastFetch < keysMinFetchInterval) {
+		return nil
+	}
+
+	keys, maxAge, err := c.fetchKeys()
+	keyCacheMetrics.Add("refreshes", 1)
+	if err != nil {
+		keyCacheMetrics.Add("refresh_failures", 1)
+		c.logger.Error("error refreshing firebase public keys", slog.Any("err", err))
+
+		c.mu.Lock()
+		c.lastFetchAt = time.Now()
+		c.mu.Unlock()
+		return err
+	}
+
+	c.mu.Lock()
+	c.keys = keys
+	c.lastFetchAt = time.Now()
+	c.expiresAt = c.lastFetchAt.Add(maxAge)
+	c.mu.Unlock()
+	return nil
+}
+
+// Keeps the keys fresh so requests rarely wait for the cert endpoint
+func (c *publicKeyCache) refreshInBackground() {
+	for {
+		c.fetch()
+
+		c.mu.RLock()
+		delay := time.Until(c.expiresAt) - keysRefreshAhead
+		c.mu.RUnlock()
+		time.Sleep(max(delay, keysRetryDelay))
+	}
+}
+
+// Unable to use Firebase admin SDK due to private Cache-Control response header without a max-age value.
+func (c *publicKeyCache) fetchKeys() (map[string]*rsa.PublicKey, time.Duration, error) {
+	resp, err := c.httpClient.Get(c.certURL)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error fetching public keys: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("error fetching public keys: status %d", resp.StatusCode)
+	}
+
+	certs := make(map[string]string)
+	if err := json.NewDecoder(resp.Body).Decode(&certs); err != nil {
+		return nil, 0, fmt.Errorf("error decoding public keys: %v", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(certs))
+	for kid, cert := range certs {
+		key, err := jwt.ParseRSAPublicKeyFromPEM([]byte(cert))
+		if err != nil {
+			return nil, 0, fmt.Errorf("error parsing public key: %v", err)
+		}
+		keys[kid] = key
+	}
+
+	return keys, keysMaxAge(resp.Header), nil
+}
+
+// Reads the max-age of the Cache-Control header, falling back to Expires
+func keysMaxAge(header http.Header) time.Duration {
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		if name != "max-age" {
+			continue
+		}
+		if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+			return time.Duration(seconds) * time.Second
+		}
+	}
+
+	if expires, err := http.ParseTime(header.Get("Expires")); err == nil && time.Until(expires) > 0 {
+		return time.Until(expires)
+	}
+
+	return defaultKeysMaxAge
+}
@@ -0,0 +1,117 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	placeDetailsURL = "https://maps.googleapis.com/maps/api/place/details/json"
+	timeZoneURL     = "https://maps.googleapis.com/maps/api/timezone/json"
+)
+
+type GooglePlace struct {
+	PlaceId           string                   `json:"place_id"`
+	Name              string                   `json:"name"`
+	AddressComponents []GoogleAddressComponent `json:"address_components"`
+	Geometry          struct {
+		Location struct {
+			Lat float64 `json:"lat"`
+			Lng float64 `json:"lng"`
+		} `json:"location"`
+	} `json:"geometry"`
+}
+
+type GoogleAddressComponent struct {
+	LongName  string   `json:"long_name"`
+	ShortName string   `json:"short_name"`
+	Types     []string `json:"types"`
+}
+
+// Long name of the first address component of the given type
+func (p GooglePlace) AddressComponent(componentType string) string {
+	for _, component := range p.AddressComponents {
+		for _, t := range component.Types {
+			if t == componentType {
+				return component.LongName
+			}
+		}
+	}
+	return ""
+}
+
+// Google Maps web services client for looking up places and time zones server side
+type GoogleMapsClient struct {
+	apiKey     string
+	httpClient *http.Client
+}
+
+func NewGoogleMapsClient(apiKey string) *GoogleMapsClient {
+	return &GoogleMapsClient{
+		apiKey:     apiKey,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (c *GoogleMapsClient) GetPlace(placeId string) (*GooglePlace, error) {
+	query := url.Values{
+		"place_id": {placeId},
+		"fields":   {"place_id,name,address_components,geometry"},
+		"key":      {c.apiKey},
+	}
+
+	var response struct {
+		Result GooglePlace `json:"result"`
+		Status string      `json:"status"`
+	}
+	if err := c.get(placeDetailsURL, query, &response); err != nil {
+		return nil, err
+	}
+	if response.Status != "OK" {
+		return nil, fmt.Errorf("error getting place %s: status %s", placeId, response.Status)
+	}
+
+	return &response.Result, nil
+}
+
+// IANA time zone id, e.g. America/Toronto, of the given location
+func (c *GoogleMapsClient) GetTimeZone(latitude, longitude float64) (string, error) {
+	query := url.Values{
+		"location":  {fmt.Sprintf("%f,%f", latitude, longitude)},
+		"timestamp": {fmt.Sprintf("%d", time.Now().Unix())},
+		"key":       {c.apiKey},
+	}
+
+	var response struct {
+		TimeZoneId string `json:"timeZoneId"`
+		Status     string `json:"status"`
+	}
+	if err := c.get(timeZoneURL, query, &response); err != nil {
+		return "", err
+	}
+	if response.Status != "OK" {
+		return "", fmt.Errorf("error getting time zone: status %s", response.Status)
+	}
+
+	return response.TimeZoneId, nil
+}
+
+func (c *GoogleMapsClient) get(endpoint string, query url.Values, response interface{}) error {
+	resp, err := c.httpClient.Get(endpoint + "?" + query.Encode())
+	if err != nil {
+		return fmt.Errorf("error calling google maps api: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("error calling google maps api: status %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+		return fmt.Errorf("error decoding google maps response: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package rest_api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/sportspazz/service/city"
+)
+
+const (
+	defaultCitiesLimit = 20
+	maxCitiesLimit     = 100
+)
+
+type CityHandler struct {
+	cityService *city.CityService
+}
+
+func NewCityHandler(cityService *city.CityService) *CityHandler {
+	return &CityHandler{
+		cityService: cityService,
+	}
+}
+
+func (h *CityHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/cities", h.searchCities).Methods(http.MethodGet)
+}
+
+// Lists the cities having places, optionally filtered by a name prefix
+func (h *CityHandler) searchCities(w http.ResponseWriter, r *http.Request) {
+	limit := defaultCitiesLimit
+	if limitParam := r.URL.Query().Get("limit"); limitParam != "" {
+		parsed, err := strconv.Atoi(limitParam)
+		if err != nil || parsed < 1 {
+			ErrorJsonResponse(w, "limit must be a positive number")
+			return
+		}
+		limit = min(parsed, maxCitiesLimit)
+	}
+
+	cities := []CityResponse{}
+	for _, c := range h.cityService.SearchCities(strings.TrimSpace(r.URL.Query().Get("q")), limit) {
+		cities = append(cities, CityResponse{
+			Slug:      c.Slug,
+			PlaceId:   c.PlaceId,
+			Name:      c.Name,
+			Region:    c.Region,
+			Country:   c.Country,
+			Latitude:  c.Latitude,
+			Longitude: c.Longitude,
+			TimeZone:  c.TimeZone,
+			PoiCount:  c.PoiCount,
+		})
+	}
+
+	JsonResponse(cities, w)
+}
@@ -0,0 +1,13 @@
+package rest_api
+
+type CityResponse struct {
+	Slug      string  `json:"slug"`
+	PlaceId   string  `json:"place_id"`
+	Name      string  `json:"name"`
+	Region    string  `json:"region"`
+	Country   string  `json:"country"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	TimeZone  string  `json:"time_zone"`
+	PoiCount  int64   `json:"poi_count"`
+}
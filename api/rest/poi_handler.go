@@ -1,40 +1,54 @@
 package rest_api
 
 import (
-	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
-	"cloud.google.com/go/storage"
 	"github.com/go-playground/validator/v10"
-	"github.com/google/uuid"
 	"github.com/gorilla/mux"
-	"github.com/sportspazz/api/client"
+	"github.com/sportspazz/identity"
 	"github.com/sportspazz/middleware"
 	"github.com/sportspazz/service/poi"
+	"github.com/sportspazz/service/sport"
+	"github.com/sportspazz/service/user"
 	"github.com/sportspazz/utils"
 )
 
 type PoiHandler struct {
-	poiService     *poi.PoiService
-	firebaseClient *client.FirebaseClient
-	cloudStorage   *storage.Client
-	bucket         string
+	poiService       *poi.PoiService
+	identityProvider identity.IdentityProvider
+	userService      *user.UserService
 }
 
-func NewPoiHandler(poiService *poi.PoiService, firebaseClient *client.FirebaseClient, cloudStorage *storage.Client, bucket string) *PoiHandler {
+func NewPoiHandler(poiService *poi.PoiService, identityProvider identity.IdentityProvider, userService *user.UserService) *PoiHandler {
 	return &PoiHandler{
-		poiService:     poiService,
-		firebaseClient: firebaseClient,
-		cloudStorage:   cloudStorage,
-		bucket:         bucket,
+		poiService:       poiService,
+		identityProvider: identityProvider,
+		userService:      userService,
 	}
 }
 
 func (h *PoiHandler) RegisterRoutes(router *mux.Router) {
-	router.Handle("/pois", middleware.RestAuthMiddleware(http.HandlerFunc(h.createPoi), h.firebaseClient)).Methods(http.MethodPost)
+	router.Handle("/pois", middleware.RestAuthMiddleware(http.HandlerFunc(h.createPoi), h.identityProvider, h.userService)).Methods(http.MethodPost)
+	router.HandleFunc("/pois/geojson", h.getPoisGeoJson).Methods(http.MethodGet)
+	router.HandleFunc("/pois/{id}", h.getPoi).Methods(http.MethodGet)
+	router.Handle("/pois/{id}", middleware.RestAuthMiddleware(http.HandlerFunc(h.updatePoi), h.identityProvider, h.userService)).Methods(http.MethodPatch)
+	router.Handle("/pois/{id}", middleware.RestAuthMiddleware(http.HandlerFunc(h.deletePoi), h.identityProvider, h.userService)).Methods(http.MethodDelete)
+	router.HandleFunc("/pois/{id}/revisions", h.getPoiRevisions).Methods(http.MethodGet)
+	router.HandleFunc("/pois/{id}/reviews", h.getReviews).Methods(http.MethodGet)
+	router.Handle("/pois/{id}/reviews", middleware.RestAuthMiddleware(http.HandlerFunc(h.createReview), h.identityProvider, h.userService)).Methods(http.MethodPost)
+	router.Handle("/pois/{id}/reviews/{reviewId}", middleware.RestAuthMiddleware(http.HandlerFunc(h.updateReview), h.identityProvider, h.userService)).Methods(http.MethodPatch)
+	router.Handle("/pois/{id}/reviews/{reviewId}", middleware.RestAuthMiddleware(http.HandlerFunc(h.deleteReview), h.identityProvider, h.userService)).Methods(http.MethodDelete)
+	router.Handle("/pois/{id}/reports", middleware.RestAuthMiddleware(http.HandlerFunc(h.reportPoi), h.identityProvider, h.userService)).Methods(http.MethodPost)
+	router.Handle("/pois/{id}/suggestions", middleware.RestAuthMiddleware(http.HandlerFunc(h.suggestEdit), h.identityProvider, h.userService)).Methods(http.MethodPost)
+	router.Handle("/pois/{id}/revisions/{revisionId}/revert", middleware.RestAuthMiddleware(middleware.RequireRole(http.HandlerFunc(h.revertPoi), user.RoleAdmin), h.identityProvider, h.userService)).Methods(http.MethodPost)
+	router.Handle("/pois/{id}/merge", middleware.RestAuthMiddleware(middleware.RequireRole(http.HandlerFunc(h.mergePoi), user.RoleAdmin), h.identityProvider, h.userService)).Methods(http.MethodPost)
+	router.Handle("/pois/{id}/restore", middleware.RestAuthMiddleware(middleware.RequireRole(http.HandlerFunc(h.restorePoi), user.RoleAdmin), h.identityProvider, h.userService)).Methods(http.MethodPost)
 }
 
 func (p *PoiHandler) createPoi(w http.ResponseWriter, r *http.Request) {
@@ -49,34 +63,33 @@ func (p *PoiHandler) createPoi(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if poiRequest.GooglePlaceId != "" && p.poiService.GetPoiByGooglePlaceId(poiRequest.GooglePlaceId) != nil {
-		ErrorJsonResponseWithCode(w, http.StatusConflict,
-			fmt.Sprintf("Place with google place id %s already exists", poiRequest.GooglePlaceId))
+	if !utils.EmailVerified(r.Context()) {
+		ErrorJsonResponseWithCode(w, http.StatusForbidden, "email address is not verified")
 		return
 	}
 
-	if poiRequest.ThumbnailUrl != "" {
-		resp, err := http.Get(poiRequest.ThumbnailUrl)
-		if err != nil || resp.StatusCode != http.StatusOK {
-			ErrorJsonResponse(w, "cannot download image "+poiRequest.ThumbnailUrl)
-			return
-		}
-		defer resp.Body.Close()
-
-		objectName := "poi/thumbnails/" + uuid.New().String() + "/" + poiRequest.Name
-		wc := p.cloudStorage.Bucket(p.bucket).
-			Object(objectName).
-			NewWriter(context.Background())
-		defer wc.Close()
+	createdBy := r.Context().Value(utils.UserIdKey).(string)
 
-		if _, err := io.Copy(wc, resp.Body); err != nil {
-			ErrorJsonResponse(w, "cannot download image "+poiRequest.ThumbnailUrl)
+	// the same place seeded for another sport gets the sport added instead, when
+	// the caller may edit the place and it is public already
+	if poiRequest.GooglePlaceId != "" {
+		if existing := p.poiService.GetPoiByGooglePlaceId(poiRequest.GooglePlaceId); existing != nil {
+			canEdit := existing.CreatedBy == createdBy || utils.HasRole(r.Context(), user.ModeratorRoles...)
+			if !canEdit || existing.Status != poi.PoiApproved {
+				poiConflictResponse(w, existing.ID,
+					fmt.Sprintf("Place with google place id %s already exists", poiRequest.GooglePlaceId))
+				return
+			}
+			updated, err := p.poiService.AddPoiSports(existing.ID, createdBy, poiRequest.AllSportTypes())
+			if err != nil {
+				poiErrorResponse(w, err)
+				return
+			}
+			JsonResponse(toPoiResponse(*updated), w)
 			return
 		}
-		poiRequest.ThumbnailUrl = fmt.Sprintf("https://storage.googleapis.com/%s/%s", p.bucket, objectName)
 	}
 
-	createdBy := r.Context().Value(utils.UserIdKey).(string)
 	newPoi, err := p.poiService.CreatePoi(
 		createdBy,
 		poiRequest.Name,
@@ -84,31 +97,527 @@ func (p *PoiHandler) createPoi(w http.ResponseWriter, r *http.Request) {
 		poiRequest.Address,
 		poiRequest.CityId,
 		&poiRequest.GooglePlaceId,
+		poiRequest.Latitude,
+		poiRequest.Longitude,
 		poiRequest.Website,
-		poiRequest.SportType,
-		poiRequest.ThumbnailUrl,
-		poiRequest.Note)
+		poiRequest.AllSportTypes(),
+		nil,
+		poi.NewThumbnailImport(poiRequest.ThumbnailUrl, poiRequest.ThumbnailPhotoReference, poiRequest.ThumbnailAttributions),
+		poiRequest.Note,
+		utils.HasRole(r.Context(), user.TrustedRoles...))
+
+	if err != nil {
+		ErrorJsonResponse(w, err.Error())
+		return
+	}
+
+	JsonResponse(toPoiResponse(newPoi), w)
+}
+
+func (p *PoiHandler) getPoi(w http.ResponseWriter, r *http.Request) {
+	existing := p.poiService.GetPoiById(mux.Vars(r)["id"])
+	viewerId, _ := r.Context().Value(utils.UserIdKey).(string)
+	if existing == nil || !existing.VisibleTo(viewerId, utils.HasRole(r.Context(), user.ModeratorRoles...)) {
+		ErrorJsonResponseWithCode(w, http.StatusNotFound, poi.ErrPoiNotFound.Error())
+		return
+	}
+
+	JsonResponse(toPoiResponse(*existing), w)
+}
+
+func (p *PoiHandler) updatePoi(w http.ResponseWriter, r *http.Request) {
+	var updateRequest UpdatePoiRequest
+	if err := json.NewDecoder(r.Body).Decode(&updateRequest); err != nil {
+		InvalidJsonResponse(w)
+		return
+	}
+
+	if err := validator.New().Struct(updateRequest); err != nil {
+		ErrorJsonResponse(w, err.Error())
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+	existing := p.poiService.GetPoiById(id)
+	if existing == nil {
+		ErrorJsonResponseWithCode(w, http.StatusNotFound, poi.ErrPoiNotFound.Error())
+		return
+	}
+
+	// other users go through the moderated suggestions
+	updatedBy := r.Context().Value(utils.UserIdKey).(string)
+	if existing.CreatedBy != updatedBy && !utils.HasRole(r.Context(), user.ModeratorRoles...) {
+		ErrorJsonResponseWithCode(w, http.StatusForbidden,
+			"Only the creator, a moderator or an admin can edit this place, suggest an edit with POST /api/v1/pois/"+id+"/suggestions instead")
+		return
+	}
+
+	update := poi.PoiUpdate{
+		Name:        updateRequest.Name,
+		Address:     updateRequest.Address,
+		Website:     updateRequest.Website,
+		CityId:      updateRequest.CityId,
+		Latitude:    updateRequest.Latitude,
+		Longitude:   updateRequest.Longitude,
+		SportTypes:  updateRequest.SportTypes,
+		Description: updateRequest.Description,
+		Note:        updateRequest.Note,
+	}
+	// a new thumbnail is fetched in the background like those of created
+	// places, an empty url removes the thumbnail
+	if updateRequest.ThumbnailUrl != nil && *updateRequest.ThumbnailUrl != existing.ThumbnailUrl {
+		update.ThumbnailImport = poi.NewThumbnailImport(*updateRequest.ThumbnailUrl, "", nil)
+		if update.ThumbnailImport == nil {
+			update.ThumbnailUrl = updateRequest.ThumbnailUrl
+		}
+	}
+
+	updated, err := p.poiService.UpdatePoi(id, updatedBy, update)
+	if err != nil {
+		poiErrorResponse(w, err)
+		return
+	}
+
+	JsonResponse(toPoiResponse(*updated), w)
+}
+
+func (p *PoiHandler) deletePoi(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	existing := p.poiService.GetPoiById(id)
+	if existing == nil {
+		ErrorJsonResponseWithCode(w, http.StatusNotFound, poi.ErrPoiNotFound.Error())
+		return
+	}
+
+	deletedBy := r.Context().Value(utils.UserIdKey).(string)
+	if existing.CreatedBy != deletedBy && !utils.HasRole(r.Context(), user.RoleAdmin) {
+		ErrorJsonResponseWithCode(w, http.StatusForbidden, "Only the creator or an admin can delete this place")
+		return
+	}
+
+	if err := p.poiService.DeletePoi(id, deletedBy); err != nil {
+		poiErrorResponse(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (p *PoiHandler) getReviews(w http.ResponseWriter, r *http.Request) {
+	existing := p.visiblePoi(r)
+	if existing == nil {
+		ErrorJsonResponseWithCode(w, http.StatusNotFound, poi.ErrPoiNotFound.Error())
+		return
+	}
+
+	reviews := []ReviewResponse{}
+	for _, review := range p.poiService.GetReviews(existing.ID) {
+		reviews = append(reviews, toReviewResponse(review))
+	}
+	JsonResponse(reviews, w)
+}
+
+func (p *PoiHandler) createReview(w http.ResponseWriter, r *http.Request) {
+	reviewRequest, visitedOn, ok := parseReviewRequest(w, r)
+	if !ok {
+		return
+	}
+	existing := p.visiblePoi(r)
+	if existing == nil {
+		ErrorJsonResponseWithCode(w, http.StatusNotFound, poi.ErrPoiNotFound.Error())
+		return
+	}
+
+	userId := r.Context().Value(utils.UserIdKey).(string)
+	review, err := p.poiService.CreateReview(existing.ID, userId, reviewRequest.Stars, reviewRequest.Text, visitedOn)
+	if err != nil {
+		poiErrorResponse(w, err)
+		return
+	}
+
+	JsonResponse(toReviewResponse(*review), w)
+}
+
+func (p *PoiHandler) updateReview(w http.ResponseWriter, r *http.Request) {
+	reviewRequest, visitedOn, ok := parseReviewRequest(w, r)
+	if !ok {
+		return
+	}
+
+	vars := mux.Vars(r)
+	userId := r.Context().Value(utils.UserIdKey).(string)
+	review, err := p.poiService.UpdateReview(vars["id"], vars["reviewId"], userId, reviewRequest.Stars, reviewRequest.Text, visitedOn)
+	if err != nil {
+		poiErrorResponse(w, err)
+		return
+	}
+
+	JsonResponse(toReviewResponse(*review), w)
+}
+
+func (p *PoiHandler) deleteReview(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userId := r.Context().Value(utils.UserIdKey).(string)
+	moderator := utils.HasRole(r.Context(), user.ModeratorRoles...)
+	if err := p.poiService.DeleteReview(vars["id"], vars["reviewId"], userId, moderator); err != nil {
+		poiErrorResponse(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Writes the error response itself when the request is invalid
+func parseReviewRequest(w http.ResponseWriter, r *http.Request) (*ReviewRequest, *time.Time, bool) {
+	var reviewRequest ReviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&reviewRequest); err != nil {
+		InvalidJsonResponse(w)
+		return nil, nil, false
+	}
+
+	if err := validator.New().Struct(reviewRequest); err != nil {
+		ErrorJsonResponse(w, err.Error())
+		return nil, nil, false
+	}
+
+	var visitedOn *time.Time
+	if reviewRequest.VisitedOn != nil {
+		parsed, _ := time.Parse("2006-01-02", *reviewRequest.VisitedOn)
+		visitedOn = &parsed
+	}
+	return &reviewRequest, visitedOn, true
+}
+
+func (p *PoiHandler) visiblePoi(r *http.Request) *poi.Poi {
+	existing := p.poiService.GetPoiById(mux.Vars(r)["id"])
+	viewerId, _ := r.Context().Value(utils.UserIdKey).(string)
+	if existing == nil || !existing.VisibleTo(viewerId, utils.HasRole(r.Context(), user.ModeratorRoles...)) {
+		return nil
+	}
+	return existing
+}
+
+// Folds the poi into another one, the response is the poi kept
+func (p *PoiHandler) mergePoi(w http.ResponseWriter, r *http.Request) {
+	var mergeRequest MergePoiRequest
+	if err := json.NewDecoder(r.Body).Decode(&mergeRequest); err != nil {
+		InvalidJsonResponse(w)
+		return
+	}
+
+	if err := validator.New().Struct(mergeRequest); err != nil {
+		ErrorJsonResponse(w, err.Error())
+		return
+	}
+
+	mergedBy := r.Context().Value(utils.UserIdKey).(string)
+	merged, err := p.poiService.MergePoi(mux.Vars(r)["id"], mergeRequest.Into, mergedBy)
+	if err != nil {
+		poiErrorResponse(w, err)
+		return
+	}
+
+	JsonResponse(toPoiResponse(*merged), w)
+}
+
+func (p *PoiHandler) restorePoi(w http.ResponseWriter, r *http.Request) {
+	restoredBy := r.Context().Value(utils.UserIdKey).(string)
+	restored, err := p.poiService.RestorePoi(mux.Vars(r)["id"], restoredBy)
+	if err != nil {
+		poiErrorResponse(w, err)
+		return
+	}
+
+	JsonResponse(toPoiResponse(*restored), w)
+}
+
+func (p *PoiHandler) getPoiRevisions(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	existing := p.poiService.GetPoiById(id)
+	viewerId, _ := r.Context().Value(utils.UserIdKey).(string)
+	if existing == nil || !existing.VisibleTo(viewerId, utils.HasRole(r.Context(), user.ModeratorRoles...)) {
+		ErrorJsonResponseWithCode(w, http.StatusNotFound, poi.ErrPoiNotFound.Error())
+		return
+	}
+
+	revisions := []PoiRevisionResponse{}
+	for _, revision := range p.poiService.GetPoiRevisions(id) {
+		revisions = append(revisions, toPoiRevisionResponse(revision))
+	}
+	JsonResponse(revisions, w)
+}
+
+func (p *PoiHandler) revertPoi(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	revertedBy := r.Context().Value(utils.UserIdKey).(string)
+	reverted, err := p.poiService.RevertPoi(vars["id"], vars["revisionId"], revertedBy)
+	if err != nil {
+		poiErrorResponse(w, err)
+		return
+	}
+
+	JsonResponse(toPoiResponse(*reverted), w)
+}
+
+func (p *PoiHandler) reportPoi(w http.ResponseWriter, r *http.Request) {
+	var reportRequest CreatePoiReportRequest
+	if err := json.NewDecoder(r.Body).Decode(&reportRequest); err != nil {
+		InvalidJsonResponse(w)
+		return
+	}
+
+	if err := validator.New().Struct(reportRequest); err != nil {
+		ErrorJsonResponse(w, err.Error())
+		return
+	}
+
+	reason, err := poi.ParseReportReason(reportRequest.Reason)
+	if err != nil {
+		ErrorJsonResponse(w, err.Error())
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+	existing := p.poiService.GetPoiById(id)
+	reportedBy := r.Context().Value(utils.UserIdKey).(string)
+	if existing == nil || !existing.VisibleTo(reportedBy, utils.HasRole(r.Context(), user.ModeratorRoles...)) {
+		ErrorJsonResponseWithCode(w, http.StatusNotFound, poi.ErrPoiNotFound.Error())
+		return
+	}
+
+	report, err := p.poiService.ReportPoi(id, reportedBy, reason, reportRequest.DuplicateOf, reportRequest.Comment)
+	if err != nil {
+		poiErrorResponse(w, err)
+		return
+	}
+
+	JsonResponse(PoiReportResponse{
+		ID:          report.ID,
+		PoiId:       report.PoiId,
+		CreatedOn:   report.CreatedOn.Format(`2006-01-02T15:04:05.000Z`),
+		Reason:      string(report.Reason),
+		DuplicateOf: report.DuplicateOf,
+		Comment:     report.Comment,
+	}, w)
+}
+
+// Changes left out are not suggested, a moderator reviews the suggestion
+func (p *PoiHandler) suggestEdit(w http.ResponseWriter, r *http.Request) {
+	var suggestionRequest PoiSuggestionRequest
+	if err := json.NewDecoder(r.Body).Decode(&suggestionRequest); err != nil {
+		InvalidJsonResponse(w)
+		return
+	}
 
+	if err := validator.New().Struct(suggestionRequest); err != nil {
+		ErrorJsonResponse(w, err.Error())
+		return
+	}
+
+	if !utils.EmailVerified(r.Context()) {
+		ErrorJsonResponseWithCode(w, http.StatusForbidden, "email address is not verified")
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+	existing := p.poiService.GetPoiById(id)
+	suggestedBy := r.Context().Value(utils.UserIdKey).(string)
+	if existing == nil || !existing.VisibleTo(suggestedBy, utils.HasRole(r.Context(), user.ModeratorRoles...)) {
+		ErrorJsonResponseWithCode(w, http.StatusNotFound, poi.ErrPoiNotFound.Error())
+		return
+	}
+
+	suggestion, err := p.poiService.SuggestEdit(id, suggestedBy, poi.SuggestedChanges{
+		Name:        suggestionRequest.Name,
+		Address:     suggestionRequest.Address,
+		Website:     suggestionRequest.Website,
+		Description: suggestionRequest.Description,
+		Closed:      suggestionRequest.Closed,
+	}, suggestionRequest.Comment)
+	if err != nil {
+		poiErrorResponse(w, err)
+		return
+	}
+
+	JsonResponse(PoiSuggestionResponse{
+		ID:        suggestion.ID,
+		PoiId:     suggestion.PoiId,
+		CreatedOn: suggestion.CreatedOn.Format(`2006-01-02T15:04:05.000Z`),
+		Status:    string(suggestion.Status),
+		Changes:   suggestion.Changes,
+		Comment:   suggestion.Comment,
+	}, w)
+}
+
+func (p *PoiHandler) getPoisGeoJson(w http.ResponseWriter, r *http.Request) {
+	bbox, err := parseBoundingBox(r.URL.Query().Get("bbox"))
 	if err != nil {
 		ErrorJsonResponse(w, err.Error())
 		return
 	}
 
+	viewerId, _ := r.Context().Value(utils.UserIdKey).(string)
+	mapPois := p.poiService.SearchPoisInBoundingBox(bbox, r.URL.Query().Get("sport"), viewerId)
+
+	features := []GeoJsonFeature{}
+	for _, cluster := range mapPois.Clusters {
+		features = append(features, GeoJsonFeature{
+			Type: "Feature",
+			Geometry: GeoJsonGeometry{
+				Type:        "Point",
+				Coordinates: []float64{cluster.Longitude, cluster.Latitude},
+			},
+			Properties: map[string]interface{}{
+				"cluster":     true,
+				"point_count": cluster.Count,
+				"bbox":        []float64{cluster.MinLng, cluster.MinLat, cluster.MaxLng, cluster.MaxLat},
+			},
+		})
+	}
+	for _, mapPoi := range mapPois.Pois {
+		features = append(features, GeoJsonFeature{
+			Type: "Feature",
+			Id:   mapPoi.ID,
+			Geometry: GeoJsonGeometry{
+				Type:        "Point",
+				Coordinates: []float64{*mapPoi.Longitude, *mapPoi.Latitude},
+			},
+			Properties: map[string]interface{}{
+				"cluster":    false,
+				"name":       mapPoi.Name,
+				"address":    mapPoi.Address,
+				"sport_type": mapPoi.SportType,
+				"url":        "/wheretoplay/" + mapPoi.SportType + "/" + mapPoi.ID,
+			},
+		})
+	}
+
+	JsonResponse(GeoJsonFeatureCollection{
+		Type:     "FeatureCollection",
+		Features: features,
+	}, w)
+}
+
+// bbox is minLng,minLat,maxLng,maxLat as in the GeoJSON spec
+func parseBoundingBox(value string) (poi.BoundingBox, error) {
+	invalid := errors.New("bbox must be minLng,minLat,maxLng,maxLat")
+
+	parts := strings.Split(value, ",")
+	if len(parts) != 4 {
+		return poi.BoundingBox{}, invalid
+	}
+
+	coords := make([]float64, 4)
+	for i, part := range parts {
+		coord, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return poi.BoundingBox{}, invalid
+		}
+		coords[i] = coord
+	}
+
+	bbox := poi.BoundingBox{MinLng: coords[0], MinLat: coords[1], MaxLng: coords[2], MaxLat: coords[3]}
+	if bbox.MinLat < -90 || bbox.MaxLat > 90 || bbox.MinLat > bbox.MaxLat ||
+		bbox.MinLng < -180 || bbox.MaxLng > 180 {
+		return poi.BoundingBox{}, invalid
+	}
+	if bbox.MinLng > bbox.MaxLng {
+		return poi.BoundingBox{}, errors.New("bbox crossing the antimeridian is not supported")
+	}
+
+	return bbox, nil
+}
+
+func poiErrorResponse(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, poi.ErrPoiNotFound), errors.Is(err, poi.ErrRevisionNotFound), errors.Is(err, poi.ErrReviewNotFound):
+		ErrorJsonResponseWithCode(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, poi.ErrPoiAlreadyExist), errors.Is(err, poi.ErrAlreadyReported), errors.Is(err, poi.ErrAlreadyReviewed):
+		ErrorJsonResponseWithCode(w, http.StatusConflict, err.Error())
+	case errors.Is(err, poi.ErrNotReviewAuthor):
+		ErrorJsonResponseWithCode(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, poi.ErrInvalidStars), errors.Is(err, poi.ErrReviewTooLong), errors.Is(err, poi.ErrVisitInFuture):
+		ErrorJsonResponse(w, err.Error())
+	case errors.Is(err, poi.ErrReportRateLimited), errors.Is(err, poi.ErrSuggestRateLimited):
+		ErrorJsonResponseWithCode(w, http.StatusTooManyRequests, err.Error())
+	case errors.Is(err, poi.ErrInvalidDuplicate), errors.Is(err, poi.ErrMergeIntoSelf), errors.Is(err, poi.ErrEmptySuggestion):
+		ErrorJsonResponse(w, err.Error())
+	case errors.Is(err, sport.ErrNoSport), errors.Is(err, sport.ErrUnknownSport):
+		ErrorJsonResponse(w, err.Error())
+	default:
+		ErrorJsonResponseWithCode(w, http.StatusInternalServerError, "internal error")
+	}
+}
+
+func poiConflictResponse(w http.ResponseWriter, id, message string) {
+	w.Header().Set(contentTypeHeader, contentTypeJson)
+	w.WriteHeader(http.StatusConflict)
+	json.NewEncoder(w).Encode(PoiConflictResponse{
+		APIError: NewAPIError(http.StatusConflict, message),
+		ID:       id,
+	})
+}
+
+func toPoiResponse(p poi.Poi) PoiResponse {
+	dateFmt := `2006-01-02T15:04:05.000Z`
+	return PoiResponse{
+		ID:                    p.ID,
+		CreatedOn:             p.CreatedOn.Format(dateFmt),
+		UpdatedOn:             p.UpdatedOn.Format(dateFmt),
+		CreatedBy:             p.CreatedBy,
+		UpdatedBy:             p.UpdatedBy,
+		Name:                  p.Name,
+		Address:               p.Address,
+		Website:               p.Website,
+		CityId:                p.CityId,
+		Latitude:              p.Latitude,
+		Longitude:             p.Longitude,
+		SportType:             p.SportType,
+		SportTypes:            p.Sports,
+		Description:           p.Description,
+		ThumbnailUrl:          p.ThumbnailUrl,
+		ThumbnailAttributions: p.ThumbnailAttributions,
+		Note:                  p.Note,
+		Status:                string(p.Status),
+		RejectionReason:       p.RejectionReason,
+		Rating:                p.Rating,
+		RatingCount:           p.RatingCount,
+	}
+}
+
+func toReviewResponse(review poi.Review) ReviewResponse {
 	dateFmt := `2006-01-02T15:04:05.000Z`
-	poiResponse := PoiResponse{
-		ID:          newPoi.ID,
-		CreatedOn:   newPoi.CreatedOn.Format(dateFmt),
-		UpdatedOn:   newPoi.UpdatedOn.Format(dateFmt),
-		CreatedBy:   newPoi.CreatedBy,
-		UpdatedBy:   newPoi.UpdatedBy,
-		Name:        newPoi.Name,
-		Address:     newPoi.Address,
-		SportType:   newPoi.SportType,
-		Description: newPoi.Description,
-		Note:        newPoi.Note,
-	}
-
-	JsonResponse(poiResponse, w)
+	var visitedOn *string
+	if review.VisitedOn != nil {
+		formatted := review.VisitedOn.Format("2006-01-02")
+		visitedOn = &formatted
+	}
+	return ReviewResponse{
+		ID:        review.ID,
+		PoiId:     review.PoiId,
+		CreatedOn: review.CreatedOn.Format(dateFmt),
+		UpdatedOn: review.UpdatedOn.Format(dateFmt),
+		CreatedBy: review.CreatedBy,
+		Stars:     review.Stars,
+		Text:      review.Text,
+		VisitedOn: visitedOn,
+	}
+}
+
+func toPoiRevisionResponse(revision poi.PoiRevision) PoiRevisionResponse {
+	changes := revision.Changes
+	if changes == nil {
+		changes = []poi.FieldChange{}
+	}
+	return PoiRevisionResponse{
+		ID:        revision.ID,
+		PoiId:     revision.PoiId,
+		CreatedOn: revision.CreatedOn.Format(`2006-01-02T15:04:05.000Z`),
+		CreatedBy: revision.CreatedBy,
+		Action:    string(revision.Action),
+		Changes:   changes,
+		Snapshot:  revision.Snapshot,
+	}
 }
 
 func validCreatePoiRequest(poiRequest CreatePoiRequest) error {
@@ -1,27 +1,156 @@
 package rest_api
 
+import "github.com/sportspazz/service/poi"
+
 type CreatePoiRequest struct {
-	Name          string `json:"name" validate:"required,min=3,max=200"`
-	Address       string `json:"address"`
-	Website       string `json:"website"`
-	CityId        string `json:"city_id" validate:"required,min=2,max=255"`
-	GooglePlaceId string `json:"google_place_id"`
-	SportType     string `json:"sport_type" validate:"required,min=2,max=50"`
-	ThumbnailUrl  string `json:"thumbnail_url"`
-	Description   string `json:"description"`
-	Note          string `json:"note"`
+	Name          string   `json:"name" validate:"required,min=3,max=200"`
+	Address       string   `json:"address"`
+	Website       string   `json:"website"`
+	CityId        string   `json:"city_id" validate:"required,min=2,max=255"`
+	GooglePlaceId string   `json:"google_place_id"`
+	Latitude      *float64 `json:"latitude" validate:"required_with=Longitude,omitempty,min=-90,max=90"`
+	Longitude     *float64 `json:"longitude" validate:"required_with=Latitude,omitempty,min=-180,max=180"`
+	SportType     string   `json:"sport_type" validate:"omitempty,min=2,max=50"`
+	SportTypes    []string `json:"sport_types" validate:"required_without=SportType,dive,min=2,max=50"`
+	// fetched in the background, thumbnail_photo_reference for Google Places photos
+	ThumbnailUrl            string   `json:"thumbnail_url" validate:"omitempty,http_url,max=2048"`
+	ThumbnailPhotoReference string   `json:"thumbnail_photo_reference" validate:"omitempty,max=1024,excluded_with=ThumbnailUrl"`
+	ThumbnailAttributions   []string `json:"thumbnail_attributions" validate:"max=10,dive,max=1000"`
+	Description             string   `json:"description"`
+	Note                    string   `json:"note"`
+}
+
+// sport_type is still accepted for clients sending a single sport
+func (r CreatePoiRequest) AllSportTypes() []string {
+	if r.SportType == "" {
+		return r.SportTypes
+	}
+	return append([]string{r.SportType}, r.SportTypes...)
+}
+
+type UpdatePoiRequest struct {
+	Name         *string  `json:"name" validate:"omitempty,min=3,max=200"`
+	Address      *string  `json:"address"`
+	Website      *string  `json:"website"`
+	CityId       *string  `json:"city_id" validate:"omitempty,min=2,max=255"`
+	Latitude     *float64 `json:"latitude" validate:"required_with=Longitude,omitempty,min=-90,max=90"`
+	Longitude    *float64 `json:"longitude" validate:"required_with=Latitude,omitempty,min=-180,max=180"`
+	SportTypes   []string `json:"sport_types" validate:"omitempty,min=1,dive,min=2,max=50"`
+	ThumbnailUrl *string  `json:"thumbnail_url" validate:"omitempty,http_url,max=500"`
+	Description  *string  `json:"description"`
+	Note         *string  `json:"note"`
+}
+
+type CreatePoiReportRequest struct {
+	Reason      string `json:"reason" validate:"required"`
+	DuplicateOf string `json:"duplicate_of" validate:"required_if=Reason duplicate"`
+	Comment     string `json:"comment" validate:"max=1000"`
+}
+
+// Fields left out are not changed, closed asks for the place to be removed
+type PoiSuggestionRequest struct {
+	Name        *string `json:"name" validate:"omitempty,min=3,max=100"`
+	Address     *string `json:"address"`
+	Website     *string `json:"website"`
+	Description *string `json:"description" validate:"omitempty,min=50,max=8000"`
+	Closed      bool    `json:"closed"`
+	Comment     string  `json:"comment" validate:"max=1000"`
+}
+
+// Creates the review, or replaces it when updating
+type ReviewRequest struct {
+	Stars     int     `json:"stars" validate:"required,min=1,max=5"`
+	Text      string  `json:"text" validate:"max=4000"`
+	VisitedOn *string `json:"visited_on" validate:"omitempty,datetime=2006-01-02"`
+}
+
+type MergePoiRequest struct {
+	Into string `json:"into" validate:"required"`
+}
+
+// Conflict with an existing place, id is the place to use instead
+type PoiConflictResponse struct {
+	APIError
+	ID string `json:"id"`
 }
 
 type PoiResponse struct {
-	ID          string `json:"id"`
-	CreatedOn   string `json:"created_on"`
-	UpdatedOn   string `json:"updated_on"`
-	CreatedBy   string `json:"created_by"`
-	UpdatedBy   string `json:"updated_by"`
-	Name        string `json:"name"`
-	Address     string `json:"address"`
-	Website     string `json:"website"`
-	SportType   string `json:"sport_type"`
-	Description string `json:"description"`
-	Note        string `json:"note"`
+	ID                    string   `json:"id"`
+	CreatedOn             string   `json:"created_on"`
+	UpdatedOn             string   `json:"updated_on"`
+	CreatedBy             string   `json:"created_by"`
+	UpdatedBy             string   `json:"updated_by"`
+	Name                  string   `json:"name"`
+	Address               string   `json:"address"`
+	Website               string   `json:"website"`
+	CityId                string   `json:"city_id"`
+	Latitude              *float64 `json:"latitude"`
+	Longitude             *float64 `json:"longitude"`
+	SportType             string   `json:"sport_type"`
+	SportTypes            []string `json:"sport_types"`
+	Description           string   `json:"description"`
+	ThumbnailUrl          string   `json:"thumbnail_url"`
+	ThumbnailAttributions []string `json:"thumbnail_attributions,omitempty"`
+	Note                  string   `json:"note"`
+	Status                string   `json:"status"`
+	RejectionReason       *string  `json:"rejection_reason,omitempty"`
+	Rating                *float64 `json:"rating"`
+	RatingCount           int      `json:"rating_count"`
+}
+
+type ReviewResponse struct {
+	ID        string  `json:"id"`
+	PoiId     string  `json:"poi_id"`
+	CreatedOn string  `json:"created_on"`
+	UpdatedOn string  `json:"updated_on"`
+	CreatedBy string  `json:"created_by"`
+	Stars     int     `json:"stars"`
+	Text      string  `json:"text"`
+	VisitedOn *string `json:"visited_on"`
+}
+
+type PoiReportResponse struct {
+	ID          string  `json:"id"`
+	PoiId       string  `json:"poi_id"`
+	CreatedOn   string  `json:"created_on"`
+	Reason      string  `json:"reason"`
+	DuplicateOf *string `json:"duplicate_of,omitempty"`
+	Comment     string  `json:"comment"`
+}
+
+type PoiSuggestionResponse struct {
+	ID        string               `json:"id"`
+	PoiId     string               `json:"poi_id"`
+	CreatedOn string               `json:"created_on"`
+	Status    string               `json:"status"`
+	Changes   poi.SuggestedChanges `json:"changes"`
+	Comment   string               `json:"comment"`
+}
+
+type PoiRevisionResponse struct {
+	ID        string            `json:"id"`
+	PoiId     string            `json:"poi_id"`
+	CreatedOn string            `json:"created_on"`
+	CreatedBy string            `json:"created_by"`
+	Action    string            `json:"action"`
+	Changes   []poi.FieldChange `json:"changes"`
+	Snapshot  poi.PoiSnapshot   `json:"snapshot"`
+}
+
+type GeoJsonFeatureCollection struct {
+	Type     string           `json:"type"`
+	Features []GeoJsonFeature `json:"features"`
+}
+
+type GeoJsonFeature struct {
+	Type       string                 `json:"type"`
+	Id         string                 `json:"id,omitempty"`
+	Geometry   GeoJsonGeometry        `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type GeoJsonGeometry struct {
+	Type string `json:"type"`
+	// longitude, latitude
+	Coordinates []float64 `json:"coordinates"`
 }
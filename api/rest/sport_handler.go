@@ -0,0 +1,51 @@
+package rest_api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/sportspazz/service/sport"
+)
+
+type SportHandler struct {
+	sportService *sport.SportService
+}
+
+func NewSportHandler(sportService *sport.SportService) *SportHandler {
+	return &SportHandler{
+		sportService: sportService,
+	}
+}
+
+func (h *SportHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/sports", h.getSports).Methods(http.MethodGet)
+}
+
+func (h *SportHandler) getSports(w http.ResponseWriter, r *http.Request) {
+	categories := make(map[string]*SportCategoryResponse)
+	for _, category := range h.sportService.GetCategories() {
+		categories[category.ID] = &SportCategoryResponse{
+			Slug: category.Slug,
+			Name: category.Name,
+		}
+	}
+
+	sports := []SportResponse{}
+	for _, s := range h.sportService.GetSports() {
+		response := SportResponse{
+			Slug:    s.Slug,
+			Name:    s.Name,
+			Aliases: s.Aliases,
+			Icon:    s.Icon,
+		}
+		if response.Aliases == nil {
+			response.Aliases = []string{}
+		}
+		if s.CategoryId != nil {
+			response.Category = categories[*s.CategoryId]
+		}
+		sports = append(sports, response)
+	}
+
+	JsonResponse(sports, w)
+}
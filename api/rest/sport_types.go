@@ -0,0 +1,14 @@
+package rest_api
+
+type SportResponse struct {
+	Slug     string                 `json:"slug"`
+	Name     string                 `json:"name"`
+	Aliases  []string               `json:"aliases"`
+	Icon     string                 `json:"icon"`
+	Category *SportCategoryResponse `json:"category"`
+}
+
+type SportCategoryResponse struct {
+	Slug string `json:"slug"`
+	Name string `json:"name"`
+}
@@ -0,0 +1,132 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/sportspazz/api/web/templates"
+	"github.com/sportspazz/identity"
+	"github.com/sportspazz/service/session"
+	"github.com/sportspazz/service/user"
+	"github.com/sportspazz/utils"
+)
+
+// Email verification and password reset
+type AccountHandler struct {
+	userService      *user.UserService
+	sessionService   *session.SessionService
+	identityProvider identity.IdentityProvider
+	logger           *slog.Logger
+}
+
+func NewAccountHandler(userService *user.UserService, sessionService *session.SessionService, identityProvider identity.IdentityProvider, logger *slog.Logger) *AccountHandler {
+	return &AccountHandler{
+		userService:      userService,
+		sessionService:   sessionService,
+		identityProvider: identityProvider,
+		logger:           logger,
+	}
+}
+
+func (h *AccountHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/verify-email", h.verifyEmailHTML).Methods(http.MethodGet)
+	router.HandleFunc("/verify-email/resend", h.resendVerificationEmailHTML).Methods(http.MethodPost)
+	router.HandleFunc("/forgot-password", h.serveForgotPasswordPageHTML).Methods(http.MethodGet)
+	router.HandleFunc("/forgot-password", h.forgotPasswordHTML).Methods(http.MethodPost)
+	router.HandleFunc("/reset-password", h.serveResetPasswordPageHTML).Methods(http.MethodGet)
+	router.HandleFunc("/reset-password", h.resetPasswordHTML).Methods(http.MethodPost)
+}
+
+func (h *AccountHandler) verifyEmailHTML(w http.ResponseWriter, r *http.Request) {
+	title, message, success := "Email verified", "Thanks, your email address is verified.", true
+	if err := h.userService.VerifyEmail(r.URL.Query().Get("token")); err != nil {
+		title, message, success = "Email verification failed", err.Error(), false
+	}
+
+	content := templates.AccountResultPage(title, message, success)
+	if err := templates.Layout(content).Render(r.Context(), w); err != nil {
+		http.Error(w, "Error rendering page", http.StatusInternalServerError)
+	}
+}
+
+func (h *AccountHandler) resendVerificationEmailHTML(w http.ResponseWriter, r *http.Request) {
+	if !utils.Logined(r.Context()) {
+		w.Header().Set("HX-Redirect", "/login")
+		return
+	}
+
+	u := h.userService.GetUserById(r.Context().Value(utils.UserIdKey).(string))
+	if u == nil || u.EmailVerified {
+		templates.ErrorMessage("Your email address is already verified").Render(r.Context(), w)
+		return
+	}
+	if err := h.userService.SendVerificationEmail(u); err != nil {
+		h.logger.Error("cannot send verification email", slog.Any("err", err))
+		templates.ErrorMessage("Unable to send the email, please try again later").Render(r.Context(), w)
+		return
+	}
+
+	templates.AccountMessage(fmt.Sprintf("We sent a verification link to %s", u.Email)).Render(r.Context(), w)
+}
+
+func (h *AccountHandler) serveForgotPasswordPageHTML(w http.ResponseWriter, r *http.Request) {
+	if err := templates.Layout(templates.ForgotPasswordPage()).Render(r.Context(), w); err != nil {
+		http.Error(w, "Error rendering page", http.StatusInternalServerError)
+	}
+}
+
+func (h *AccountHandler) forgotPasswordHTML(w http.ResponseWriter, r *http.Request) {
+	email := r.FormValue("email")
+	if email == "" {
+		templates.ErrorMessage("Email is required").Render(r.Context(), w)
+		return
+	}
+
+	if err := h.userService.RequestPasswordReset(email); err != nil {
+		h.logger.Error("cannot send password reset email", slog.Any("err", err))
+		templates.ErrorMessage("Unable to send the email, please try again later").Render(r.Context(), w)
+		return
+	}
+
+	templates.AccountMessage("If an account exists for this email, we sent a link to reset the password.").Render(r.Context(), w)
+}
+
+func (h *AccountHandler) serveResetPasswordPageHTML(w http.ResponseWriter, r *http.Request) {
+	content := templates.ResetPasswordPage(r.URL.Query().Get("token"))
+	if err := templates.Layout(content).Render(r.Context(), w); err != nil {
+		http.Error(w, "Error rendering page", http.StatusInternalServerError)
+	}
+}
+
+func (h *AccountHandler) resetPasswordHTML(w http.ResponseWriter, r *http.Request) {
+	token := r.FormValue("token")
+	password := r.FormValue("password")
+
+	if len(password) < passwordMinLength {
+		templates.ErrorMessage(fmt.Sprintf("Password at least %d characters", passwordMinLength)).Render(r.Context(), w)
+		return
+	}
+
+	userId, err := h.userService.ResetPassword(token, password)
+	if err != nil {
+		if !errors.Is(err, user.ErrInvalidUserToken) {
+			h.logger.Error("cannot reset password", slog.Any("err", err))
+		}
+		templates.ErrorMessage(err.Error()).Render(r.Context(), w)
+		return
+	}
+
+	// whoever knew the old password is logged out
+	if err := h.sessionService.RevokeAllSessions(userId); err != nil {
+		h.logger.Error("cannot revoke sessions after password reset", slog.Any("err", err))
+	}
+	if err := h.identityProvider.Revoke(r.Context(), userId); err != nil {
+		h.logger.Error("cannot revoke refresh tokens after password reset", slog.Any("err", err))
+	}
+
+	utils.ClearSessionCookie(w)
+	w.Header().Set("HX-Redirect", "/login")
+}
@@ -3,26 +3,28 @@ package web
 import (
 	"log/slog"
 	"net/http"
-	"time"
 
 	"github.com/gorilla/mux"
-	"github.com/sportspazz/api/client"
 	"github.com/sportspazz/api/web/templates"
+	"github.com/sportspazz/identity"
+	"github.com/sportspazz/service/session"
 	"github.com/sportspazz/service/user"
 	"github.com/sportspazz/utils"
 )
 
 type LoginHandler struct {
-	userService        *user.UserService
-	firebaseRestClient *client.FirebaseClient
-	logger             *slog.Logger
+	userService      *user.UserService
+	sessionService   *session.SessionService
+	identityProvider identity.IdentityProvider
+	logger           *slog.Logger
 }
 
-func NewLoginHandler(userService *user.UserService, firebaseRestClient *client.FirebaseClient, logger *slog.Logger) *LoginHandler {
+func NewLoginHandler(userService *user.UserService, sessionService *session.SessionService, identityProvider identity.IdentityProvider, logger *slog.Logger) *LoginHandler {
 	return &LoginHandler{
-		userService:        userService,
-		firebaseRestClient: firebaseRestClient,
-		logger:             logger,
+		userService:      userService,
+		sessionService:   sessionService,
+		identityProvider: identityProvider,
+		logger:           logger,
 	}
 }
 
@@ -51,34 +53,31 @@ func (h *LoginHandler) loginHTML(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	resp, err := h.firebaseRestClient.SignInWithEmailAndPassword(client.NewSignInWithPasswordRequest(email, password))
+	tokens, err := h.identityProvider.SignIn(r.Context(), email, password)
 	if err != nil {
 		templates.LoginError("Invalid email or passowrd").Render(r.Context(), w)
 		return
 	}
 
-	http.SetCookie(w, &http.Cookie{
-		Name:     string(utils.IdTokenKey),
-		Value:    resp.IdToken,
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   true,
-		Expires:  time.Now().Add(2 * time.Hour),
-	})
+	token, s, err := h.sessionService.CreateSession(tokens.UserId, r.UserAgent(), utils.ClientIp(r))
+	if err != nil {
+		templates.LoginError("Unable to login due to internal error").Render(r.Context(), w)
+		return
+	}
 
-	http.SetCookie(w, &http.Cookie{
-		Name:     string(utils.RefreshTokenKey),
-		Value:    resp.RefreshToken,
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   true,
-		Expires:  time.Now().Add(24 * time.Hour),
-	})
+	utils.SetSessionCookie(w, token, s.ExpiresOn)
 	w.Header().Set("HX-Redirect", "/")
 }
 
 func (h *LoginHandler) logoutHTML(w http.ResponseWriter, r *http.Request) {
-	utils.ClearTokenCookies(w)
+	if utils.Logined(r.Context()) {
+		userId := r.Context().Value(utils.UserIdKey).(string)
+		sessionId := r.Context().Value(utils.SessionIdKey).(string)
+		if err := h.sessionService.RevokeSession(userId, sessionId); err != nil {
+			h.logger.Error("not able to revoke session", slog.Any("err", err))
+		}
+	}
+	utils.ClearSessionCookie(w)
 
 	w.Header().Set("HX-Redirect", "/")
 }
@@ -0,0 +1,277 @@
+package web
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+
+	"github.com/gorilla/mux"
+	"github.com/sportspazz/api/web/templates"
+	"github.com/sportspazz/blob"
+	"github.com/sportspazz/imaging"
+	"github.com/sportspazz/middleware"
+	"github.com/sportspazz/service/poi"
+	"github.com/sportspazz/service/user"
+	"github.com/sportspazz/utils"
+)
+
+const moderationPageSize = 50
+
+type ModerationHandler struct {
+	poiService  *poi.PoiService
+	userService *user.UserService
+	blobStore   blob.BlobStore
+	logger      *slog.Logger
+}
+
+func NewModerationHandler(poiService *poi.PoiService, userService *user.UserService, blobStore blob.BlobStore, logger *slog.Logger) *ModerationHandler {
+	return &ModerationHandler{
+		poiService:  poiService,
+		userService: userService,
+		blobStore:   blobStore,
+		logger:      logger,
+	}
+}
+
+func (h *ModerationHandler) RegisterRoutes(router *mux.Router) {
+	router.Handle("/admin/moderation", middleware.RequireRole(http.HandlerFunc(h.serveModerationPageHTML), user.ModeratorRoles...)).Methods(http.MethodGet)
+	router.Handle("/admin/moderation/{id}/approve", middleware.RequireRole(http.HandlerFunc(h.approvePoiHTML), user.ModeratorRoles...)).Methods(http.MethodPost)
+	router.Handle("/admin/moderation/{id}/reject", middleware.RequireRole(http.HandlerFunc(h.rejectPoiHTML), user.ModeratorRoles...)).Methods(http.MethodPost)
+	router.Handle("/admin/suggestions", middleware.RequireRole(http.HandlerFunc(h.serveSuggestionsPageHTML), user.ModeratorRoles...)).Methods(http.MethodGet)
+	router.Handle("/admin/suggestions/{id}/accept", middleware.RequireRole(http.HandlerFunc(h.acceptSuggestionHTML), user.ModeratorRoles...)).Methods(http.MethodPost)
+	router.Handle("/admin/reports", middleware.RequireRole(http.HandlerFunc(h.serveReportsPageHTML), user.ModeratorRoles...)).Methods(http.MethodGet)
+	router.Handle("/admin/reports/{poiId}/dismiss", middleware.RequireRole(http.HandlerFunc(h.dismissReportsHTML), user.ModeratorRoles...)).Methods(http.MethodPost)
+	router.Handle("/admin/reports/{poiId}/delete", middleware.RequireRole(http.HandlerFunc(h.deleteReportedPoiHTML), user.ModeratorRoles...)).Methods(http.MethodPost)
+	router.Handle("/admin/reports/{poiId}/merge", middleware.RequireRole(http.HandlerFunc(h.mergeReportedPoiHTML), user.RoleAdmin)).Methods(http.MethodPost)
+	router.Handle("/admin/suggestions/{id}/reject", middleware.RequireRole(http.HandlerFunc(h.rejectSuggestionHTML), user.ModeratorRoles...)).Methods(http.MethodPost)
+	router.Handle("/admin/photos", middleware.RequireRole(http.HandlerFunc(h.servePhotosPageHTML), user.ModeratorRoles...)).Methods(http.MethodGet)
+	router.Handle("/admin/photos/{id}/approve", middleware.RequireRole(http.HandlerFunc(h.approvePhotoHTML), user.ModeratorRoles...)).Methods(http.MethodPost)
+	router.Handle("/admin/photos/{id}/reject", middleware.RequireRole(http.HandlerFunc(h.rejectPhotoHTML), user.ModeratorRoles...)).Methods(http.MethodPost)
+}
+
+func (h *ModerationHandler) serveModerationPageHTML(w http.ResponseWriter, r *http.Request) {
+	content := templates.ModerationPage(h.poiService.GetPendingPois(moderationPageSize))
+	if err := templates.Layout(content).Render(r.Context(), w); err != nil {
+		http.Error(w, "Error rendering page", http.StatusInternalServerError)
+	}
+}
+
+func (h *ModerationHandler) approvePoiHTML(w http.ResponseWriter, r *http.Request) {
+	moderatedBy := r.Context().Value(utils.UserIdKey).(string)
+	approved, err := h.poiService.ApprovePoi(mux.Vars(r)["id"], moderatedBy)
+	if err != nil {
+		h.moderationError(w, r, err)
+		return
+	}
+
+	placePath := "/wheretoplay/" + url.PathEscape(approved.SportType) + "/" + approved.ID
+	if err := h.userService.SendPlaceApprovedEmail(approved.CreatedBy, approved.Name, placePath); err != nil {
+		h.logger.Error("Unable to send place approved email", slog.String("poiId", approved.ID), slog.Any("err", err))
+	}
+
+	templates.ModerationResult(*approved).Render(r.Context(), w)
+}
+
+func (h *ModerationHandler) rejectPoiHTML(w http.ResponseWriter, r *http.Request) {
+	moderatedBy := r.Context().Value(utils.UserIdKey).(string)
+	rejected, err := h.poiService.RejectPoi(mux.Vars(r)["id"], moderatedBy, r.FormValue("reason"))
+	if err != nil {
+		h.moderationError(w, r, err)
+		return
+	}
+
+	templates.ModerationResult(*rejected).Render(r.Context(), w)
+}
+
+// Renders the place again with the error, so the moderator can retry
+func (h *ModerationHandler) moderationError(w http.ResponseWriter, r *http.Request, err error) {
+	existing := h.poiService.GetPoiById(mux.Vars(r)["id"])
+
+	var message string
+	switch {
+	case existing == nil, errors.Is(err, poi.ErrPoiNotFound):
+		templates.ErrorMessage("The place no longer exists").Render(r.Context(), w)
+		return
+	case errors.Is(err, poi.ErrNoRejectReason):
+		message = "Please give a reason for rejecting the place"
+	case errors.Is(err, poi.ErrPoiNotPending):
+		templates.ModerationResult(*existing).Render(r.Context(), w)
+		return
+	default:
+		message = "Unable to moderate the place, please try again"
+	}
+
+	templates.ModerationItem(*existing, message).Render(r.Context(), w)
+}
+
+func (h *ModerationHandler) serveSuggestionsPageHTML(w http.ResponseWriter, r *http.Request) {
+	content := templates.SuggestionsPage(h.poiService.GetPendingSuggestions(moderationPageSize))
+	if err := templates.Layout(content).Render(r.Context(), w); err != nil {
+		http.Error(w, "Error rendering page", http.StatusInternalServerError)
+	}
+}
+
+func (h *ModerationHandler) acceptSuggestionHTML(w http.ResponseWriter, r *http.Request) {
+	reviewedBy := r.Context().Value(utils.UserIdKey).(string)
+	accepted, err := h.poiService.AcceptSuggestion(mux.Vars(r)["id"], reviewedBy)
+	if err != nil {
+		h.suggestionError(w, r, err)
+		return
+	}
+
+	templates.SuggestionResult(*accepted).Render(r.Context(), w)
+}
+
+func (h *ModerationHandler) rejectSuggestionHTML(w http.ResponseWriter, r *http.Request) {
+	reviewedBy := r.Context().Value(utils.UserIdKey).(string)
+	rejected, err := h.poiService.RejectSuggestion(mux.Vars(r)["id"], reviewedBy, r.FormValue("note"))
+	if err != nil {
+		h.suggestionError(w, r, err)
+		return
+	}
+
+	templates.SuggestionResult(*rejected).Render(r.Context(), w)
+}
+
+// Renders the suggestion again with the error, so the moderator can retry
+func (h *ModerationHandler) suggestionError(w http.ResponseWriter, r *http.Request, err error) {
+	suggestion := h.poiService.GetSuggestionById(mux.Vars(r)["id"])
+
+	var message string
+	switch {
+	case suggestion == nil:
+		templates.ErrorMessage("The suggestion no longer exists").Render(r.Context(), w)
+		return
+	case errors.Is(err, poi.ErrSuggestionNotPending):
+		templates.SuggestionResult(*suggestion).Render(r.Context(), w)
+		return
+	case errors.Is(err, poi.ErrPoiNotFound):
+		message = "The place no longer exists, reject the suggestion"
+	default:
+		h.logger.Error("not able to review edit suggestion", slog.Any("err", err))
+		message = "Unable to review the suggestion, please try again"
+	}
+
+	templates.SuggestionItem(*suggestion, message).Render(r.Context(), w)
+}
+
+func (h *ModerationHandler) serveReportsPageHTML(w http.ResponseWriter, r *http.Request) {
+	content := templates.ReportsPage(h.poiService.GetReportedPois())
+	if err := templates.Layout(content).Render(r.Context(), w); err != nil {
+		http.Error(w, "Error rendering page", http.StatusInternalServerError)
+	}
+}
+
+func (h *ModerationHandler) dismissReportsHTML(w http.ResponseWriter, r *http.Request) {
+	poiId := mux.Vars(r)["poiId"]
+	resolvedBy := r.Context().Value(utils.UserIdKey).(string)
+	if err := h.poiService.DismissReports(poiId, resolvedBy); err != nil {
+		h.reportError(w, r, err)
+		return
+	}
+
+	templates.ReportResult(poiId, "The reports were dismissed").Render(r.Context(), w)
+}
+
+func (h *ModerationHandler) deleteReportedPoiHTML(w http.ResponseWriter, r *http.Request) {
+	poiId := mux.Vars(r)["poiId"]
+	resolvedBy := r.Context().Value(utils.UserIdKey).(string)
+	if err := h.poiService.DeleteReportedPoi(poiId, resolvedBy); err != nil {
+		h.reportError(w, r, err)
+		return
+	}
+
+	templates.ReportResult(poiId, "The place was deleted").Render(r.Context(), w)
+}
+
+// The reported place is a duplicate, it is folded into the place it duplicates
+func (h *ModerationHandler) mergeReportedPoiHTML(w http.ResponseWriter, r *http.Request) {
+	poiId := mux.Vars(r)["poiId"]
+	mergedBy := r.Context().Value(utils.UserIdKey).(string)
+	merged, err := h.poiService.MergePoi(poiId, r.FormValue("into"), mergedBy)
+	if err != nil {
+		h.reportError(w, r, err)
+		return
+	}
+
+	templates.ReportResult(poiId, "The place was merged into "+merged.Name).Render(r.Context(), w)
+}
+
+// Renders the reported place again with the error, so the moderator can retry
+func (h *ModerationHandler) reportError(w http.ResponseWriter, r *http.Request, err error) {
+	poiId := mux.Vars(r)["poiId"]
+	if errors.Is(err, poi.ErrPoiNotFound) {
+		templates.ReportResult(poiId, "The place no longer exists").Render(r.Context(), w)
+		return
+	}
+
+	h.logger.Error("not able to resolve poi reports", slog.String("poiId", poiId), slog.Any("err", err))
+	for _, reported := range h.poiService.GetReportedPois() {
+		if reported.Poi.ID == poiId {
+			templates.ReportedPoiItem(reported, "Unable to resolve the reports, please try again").Render(r.Context(), w)
+			return
+		}
+	}
+	templates.ReportResult(poiId, "The reports were already resolved").Render(r.Context(), w)
+}
+
+func (h *ModerationHandler) servePhotosPageHTML(w http.ResponseWriter, r *http.Request) {
+	content := templates.PhotosPage(h.poiService.GetPendingPhotos(moderationPageSize))
+	if err := templates.Layout(content).Render(r.Context(), w); err != nil {
+		http.Error(w, "Error rendering page", http.StatusInternalServerError)
+	}
+}
+
+func (h *ModerationHandler) approvePhotoHTML(w http.ResponseWriter, r *http.Request) {
+	moderatedBy := r.Context().Value(utils.UserIdKey).(string)
+	approved, err := h.poiService.ApprovePhoto(mux.Vars(r)["id"], moderatedBy)
+	if err != nil {
+		h.photoError(w, r, err)
+		return
+	}
+
+	templates.PhotoResult(approved.ID, "The photo was approved").Render(r.Context(), w)
+}
+
+// Rejected photos are removed from the blob store, they are never shown
+func (h *ModerationHandler) rejectPhotoHTML(w http.ResponseWriter, r *http.Request) {
+	moderatedBy := r.Context().Value(utils.UserIdKey).(string)
+	rejected, err := h.poiService.RejectPhoto(mux.Vars(r)["id"], moderatedBy)
+	if err != nil {
+		h.photoError(w, r, err)
+		return
+	}
+	if rejected.ObjectName != nil {
+		if err := h.blobStore.Delete(r.Context(), *rejected.ObjectName); err != nil && !errors.Is(err, blob.ErrBlobNotFound) {
+			h.logger.Error("not able to delete rejected photo", slog.String("id", rejected.ID), slog.Any("err", err))
+		}
+	}
+	if err := imaging.Delete(r.Context(), h.blobStore, rejected.Renditions); err != nil {
+		h.logger.Error("not able to delete rejected photo", slog.String("id", rejected.ID), slog.Any("err", err))
+	}
+
+	templates.PhotoResult(rejected.ID, "The photo was rejected").Render(r.Context(), w)
+}
+
+// Renders the photo again with the error, so the moderator can retry
+func (h *ModerationHandler) photoError(w http.ResponseWriter, r *http.Request, err error) {
+	id := mux.Vars(r)["id"]
+	if errors.Is(err, poi.ErrPhotoNotFound) {
+		templates.PhotoResult(id, "The photo no longer exists").Render(r.Context(), w)
+		return
+	}
+	if errors.Is(err, poi.ErrPhotoNotPending) {
+		templates.PhotoResult(id, "The photo was already moderated").Render(r.Context(), w)
+		return
+	}
+
+	h.logger.Error("not able to moderate photo", slog.String("id", id), slog.Any("err", err))
+	for _, photo := range h.poiService.GetPendingPhotos(moderationPageSize) {
+		if photo.ID == id {
+			templates.PhotoItem(photo, "Unable to moderate the photo, please try again").Render(r.Context(), w)
+			return
+		}
+	}
+	templates.PhotoResult(id, "Unable to moderate the photo, please try again").Render(r.Context(), w)
+}
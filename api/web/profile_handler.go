@@ -0,0 +1,50 @@
+package web
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/sportspazz/api/web/templates"
+	"github.com/sportspazz/service/poi"
+	"github.com/sportspazz/service/user"
+	"github.com/sportspazz/utils"
+)
+
+type ProfileHandler struct {
+	userService *user.UserService
+	poiService  *poi.PoiService
+	logger      *slog.Logger
+}
+
+func NewProfileHandler(userService *user.UserService, poiService *poi.PoiService, logger *slog.Logger) *ProfileHandler {
+	return &ProfileHandler{
+		userService: userService,
+		poiService:  poiService,
+		logger:      logger,
+	}
+}
+
+func (h *ProfileHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/profile", h.serveProfilePageHTML).Methods(http.MethodGet)
+}
+
+func (h *ProfileHandler) serveProfilePageHTML(w http.ResponseWriter, r *http.Request) {
+	if !utils.Logined(r.Context()) {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	userId := r.Context().Value(utils.UserIdKey).(string)
+	u := h.userService.GetUserById(userId)
+	if u == nil {
+		w.WriteHeader(http.StatusNotFound)
+		templates.Layout(templates.NotFoundMessage()).Render(r.Context(), w)
+		return
+	}
+
+	content := templates.ProfilePage(*u, h.userService.GetRoles(userId), h.poiService.GetSuggestionCounts(userId))
+	if err := templates.Layout(content).Render(r.Context(), w); err != nil {
+		http.Error(w, "Error rendering page", http.StatusInternalServerError)
+	}
+}
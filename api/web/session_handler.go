@@ -0,0 +1,92 @@
+package web
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/sportspazz/api/web/templates"
+	"github.com/sportspazz/identity"
+	"github.com/sportspazz/service/session"
+	"github.com/sportspazz/utils"
+)
+
+type SessionHandler struct {
+	sessionService   *session.SessionService
+	identityProvider identity.IdentityProvider
+	logger           *slog.Logger
+}
+
+func NewSessionHandler(sessionService *session.SessionService, identityProvider identity.IdentityProvider, logger *slog.Logger) *SessionHandler {
+	return &SessionHandler{
+		sessionService:   sessionService,
+		identityProvider: identityProvider,
+		logger:           logger,
+	}
+}
+
+func (h *SessionHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/sessions", h.serveSessionsPageHTML).Methods(http.MethodGet)
+	router.HandleFunc("/sessions/revoke-all", h.revokeAllSessionsHTML).Methods(http.MethodPost)
+	router.HandleFunc("/sessions/{id}/revoke", h.revokeSessionHTML).Methods(http.MethodPost)
+}
+
+func (h *SessionHandler) serveSessionsPageHTML(w http.ResponseWriter, r *http.Request) {
+	if !utils.Logined(r.Context()) {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	userId := r.Context().Value(utils.UserIdKey).(string)
+	currentSessionId := r.Context().Value(utils.SessionIdKey).(string)
+	content := templates.SessionsPage(h.sessionService.GetActiveSessions(userId), currentSessionId)
+	if err := templates.Layout(content).Render(r.Context(), w); err != nil {
+		http.Error(w, "Error rendering page", http.StatusInternalServerError)
+	}
+}
+
+func (h *SessionHandler) revokeSessionHTML(w http.ResponseWriter, r *http.Request) {
+	if !utils.Logined(r.Context()) {
+		w.Header().Set("HX-Redirect", "/login")
+		return
+	}
+
+	userId := r.Context().Value(utils.UserIdKey).(string)
+	sessionId := mux.Vars(r)["id"]
+	if err := h.sessionService.RevokeSession(userId, sessionId); err != nil {
+		if !errors.Is(err, session.ErrSessionNotFound) {
+			h.logger.Error("not able to revoke session", slog.Any("err", err))
+		}
+		templates.ErrorMessage("Unable to revoke the session").Render(r.Context(), w)
+		return
+	}
+
+	if sessionId == r.Context().Value(utils.SessionIdKey).(string) {
+		utils.ClearSessionCookie(w)
+		w.Header().Set("HX-Redirect", "/")
+		return
+	}
+	w.Header().Set("HX-Redirect", "/sessions")
+}
+
+// Logs out everywhere, including API clients holding refresh tokens
+func (h *SessionHandler) revokeAllSessionsHTML(w http.ResponseWriter, r *http.Request) {
+	if !utils.Logined(r.Context()) {
+		w.Header().Set("HX-Redirect", "/login")
+		return
+	}
+
+	userId := r.Context().Value(utils.UserIdKey).(string)
+	if err := h.sessionService.RevokeAllSessions(userId); err != nil {
+		h.logger.Error("not able to revoke sessions", slog.Any("err", err))
+		templates.ErrorMessage("Unable to revoke the sessions").Render(r.Context(), w)
+		return
+	}
+	if err := h.identityProvider.Revoke(r.Context(), userId); err != nil {
+		h.logger.Error("not able to revoke refresh tokens", slog.Any("err", err))
+	}
+
+	utils.ClearSessionCookie(w)
+	w.Header().Set("HX-Redirect", "/login")
+}
@@ -2,43 +2,69 @@ package web
 
 import (
 	"encoding/json"
+	"errors"
 	"io"
 	"log/slog"
+	"math"
+	"mime/multipart"
 	"net/http"
+	"net/url"
+	"path"
 	"strconv"
+	"strings"
+	"time"
 
 	"fmt"
 
-	"cloud.google.com/go/storage"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/sportspazz/api/web/templates"
 	"github.com/sportspazz/api/web/types"
+	"github.com/sportspazz/blob"
+	"github.com/sportspazz/imaging"
+	"github.com/sportspazz/middleware"
+	"github.com/sportspazz/service/city"
 	"github.com/sportspazz/service/poi"
+	"github.com/sportspazz/service/sport"
+	"github.com/sportspazz/service/user"
 	"github.com/sportspazz/utils"
 )
 
-const maxThumbnailSize = 100 * 1024 // 100 KB
+const maxPhotosPerUpload = 10
 
 const cityPlaceIdParam = "cityPlaceId"
+const cityParam = "city"
+const queryParam = "q"
 const sportParam = "sport"
 const pageSizeParam = "pageSize"
 const cursorParam = "cursor"
+const latParam = "lat"
+const lngParam = "lng"
+const radiusKmParam = "radiusKm"
+const sortParam = "sort"
+
+const defaultRadiusKm = 10.0
+const maxRadiusKm = 100.0
+
+const defaultPageSize = 15
+const maxPageSize = 50
 
 type WhereToPlayHandler struct {
 	logger          *slog.Logger
 	poiService      *poi.PoiService
-	cloudStorage    *storage.Client
-	bucket          string
+	sportService    *sport.SportService
+	cityService     *city.CityService
+	blobStore       blob.BlobStore
 	googleMapApiKey string
 }
 
-func NewWhereToPlayHandler(logger *slog.Logger, poiService *poi.PoiService, cloudStorage *storage.Client, bucket, googleMapApiKey string) *WhereToPlayHandler {
+func NewWhereToPlayHandler(logger *slog.Logger, poiService *poi.PoiService, sportService *sport.SportService, cityService *city.CityService, blobStore blob.BlobStore, googleMapApiKey string) *WhereToPlayHandler {
 	return &WhereToPlayHandler{
 		logger:          logger,
 		poiService:      poiService,
-		cloudStorage:    cloudStorage,
-		bucket:          bucket,
+		sportService:    sportService,
+		cityService:     cityService,
+		blobStore:       blobStore,
 		googleMapApiKey: googleMapApiKey,
 	}
 }
@@ -48,41 +74,424 @@ func (h *WhereToPlayHandler) RegisterRoutes(router *mux.Router) {
 	router.HandleFunc("/wheretoplay/search", h.searchWhereToPlay).Methods(http.MethodGet)
 	router.HandleFunc("/wheretoplay/new", h.serveCreateNewPlacePageHTML).Methods(http.MethodGet)
 	router.HandleFunc("/wheretoplay/new", h.createNewPlace).Methods(http.MethodPost)
-	router.HandleFunc("/wheretoplay/{sport}/{placeId}", h.placeDetails).Methods(http.MethodGet)
+	router.HandleFunc("/wheretoplay/new/duplicates", h.checkDuplicates).Methods(http.MethodGet)
+	router.HandleFunc("/wheretoplay/{sport}/{placeId}", h.placeDetails).Methods(http.MethodGet).MatcherFunc(isPlaceDetailsUrl)
+	router.HandleFunc("/wheretoplay/{sport}/{placeId}/history", h.placeHistory).Methods(http.MethodGet)
+	router.HandleFunc("/wheretoplay/{sport}/{placeId}/suggest", h.serveSuggestEditFormHTML).Methods(http.MethodGet)
+	router.HandleFunc("/wheretoplay/{sport}/{placeId}/photos", h.uploadPhotos).Methods(http.MethodPost)
+	router.HandleFunc("/wheretoplay/{sport}/{placeId}/photos/{photoId}/cover", h.setCoverPhoto).Methods(http.MethodPost)
+	router.HandleFunc("/wheretoplay/{sport}/{placeId}/reviews", h.placeReviews).Methods(http.MethodGet)
+	router.HandleFunc("/wheretoplay/{sport}/{placeId}/reviews", h.saveReview).Methods(http.MethodPost)
+	router.HandleFunc("/wheretoplay/{sport}/{placeId}/reviews/{reviewId}/delete", h.deleteReview).Methods(http.MethodPost)
+	router.HandleFunc("/wheretoplay/{sport}/{placeId}/suggestions", h.suggestEdit).Methods(http.MethodPost)
+	router.Handle("/wheretoplay/{sport}/{placeId}/revisions/{revisionId}/revert", middleware.RequireRole(http.HandlerFunc(h.revertPlace), user.RoleAdmin)).Methods(http.MethodPost)
+	router.HandleFunc("/wheretoplay/{citySlug}/{sport}", h.serveCitySportPageHTML).Methods(http.MethodGet)
 }
 
-func (h *WhereToPlayHandler) placeDetails(w http.ResponseWriter, r *http.Request) {
+// Place details urls end with the poi id, city pages with a sport slug
+func isPlaceDetailsUrl(r *http.Request, _ *mux.RouteMatch) bool {
+	_, err := uuid.Parse(path.Base(r.URL.Path))
+	return err == nil
+}
+
+func (h *WhereToPlayHandler) serveCitySportPageHTML(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	placeId := vars["placeId"]
 
-	poi := h.poiService.GetPoiById(placeId)
-	if poi == nil {
-		content := templates.NotFoundMessage()
-		err := templates.Layout(content).Render(r.Context(), w)
+	city := h.cityService.GetCityBySlug(vars["citySlug"])
+	sports, err := h.sportService.ResolveSports([]string{vars["sport"]})
+	if city == nil || err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		templates.Layout(templates.NotFoundMessage()).Render(r.Context(), w)
+		return
+	}
+	sport := sports[0]
 
-		if err != nil {
+	pageSize := defaultPageSize
+	viewerId, _ := r.Context().Value(utils.UserIdKey).(string)
+	pois := h.poiService.SearchPois(city.PlaceId, sport.Slug, viewerId, poi.SortDefault, "", pageSize)
+
+	nextPageUrl := ""
+	if pois.Cursor != "" {
+		nextPageUrl = fmt.Sprintf("/wheretoplay/search?sport=%s&cityPlaceId=%s&pageSize=%d&cursor=%s",
+			url.QueryEscape(sport.Slug), url.QueryEscape(city.PlaceId), pageSize, url.QueryEscape(pois.Cursor))
+	}
+
+	content := templates.CitySportPage(*city, sport, pois, nextPageUrl)
+	if err := templates.Layout(content).Render(r.Context(), w); err != nil {
+		http.Error(w, "Error rendering page", http.StatusInternalServerError)
+	}
+}
+
+func (h *WhereToPlayHandler) placeDetails(w http.ResponseWriter, r *http.Request) {
+	poi := h.visiblePoi(r)
+	if poi == nil {
+		// links to merged duplicates keep working
+		if merged := h.poiService.GetMergedPoi(mux.Vars(r)["placeId"]); merged != nil {
+			http.Redirect(w, r, "/wheretoplay/"+url.PathEscape(merged.SportType)+"/"+merged.ID, http.StatusMovedPermanently)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		if err := templates.Layout(templates.NotFoundMessage()).Render(r.Context(), w); err != nil {
 			http.Error(w, "Error rendering page", http.StatusInternalServerError)
 		}
 		return
 	}
+
+	// places added on the site have no Google details
+	details := types.Result{
+		Name:             poi.Name,
+		FormattedAddress: poi.Address,
+		Website:          poi.Website,
+	}
 	if poi.GooglePlaceId != nil {
-		details, err := getGooglePlaceDetails(*poi.GooglePlaceId, h.googleMapApiKey)
-		if err == nil && details.Status == "OK" {
-			w.WriteHeader(http.StatusOK)
+		googleDetails, err := getGooglePlaceDetails(*poi.GooglePlaceId, h.googleMapApiKey)
+		if err == nil && googleDetails.Status == "OK" {
+			details = googleDetails.Result
+		} else {
+			h.logger.Error("Cannot get place details from google api", slog.Any("err", err), slog.Any("details", googleDetails))
+		}
+	}
 
-			content := templates.PlaceDetais(details.Result)
-			err := templates.MapLayout(content).Render(r.Context(), w)
+	viewerId, _ := r.Context().Value(utils.UserIdKey).(string)
+	content := templates.PlaceDetais(*poi, details, h.poiService.GetPhotos(poi.ID, viewerId))
+	if err := templates.MapLayout(content).Render(r.Context(), w); err != nil {
+		http.Error(w, "Error rendering page", http.StatusInternalServerError)
+	}
+}
 
-			if err != nil {
-				http.Error(w, "Error rendering page", http.StatusInternalServerError)
-			}
+func (h *WhereToPlayHandler) placeHistory(w http.ResponseWriter, r *http.Request) {
+	poi := h.visiblePoi(r)
+	if poi == nil {
+		w.WriteHeader(http.StatusNotFound)
+		templates.NotFoundMessage().Render(r.Context(), w)
+		return
+	}
+
+	templates.PlaceHistory(*poi, h.poiService.GetPoiRevisions(poi.ID)).Render(r.Context(), w)
+}
+
+func (h *WhereToPlayHandler) revertPlace(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	revertedBy := r.Context().Value(utils.UserIdKey).(string)
+	reverted, err := h.poiService.RevertPoi(vars["placeId"], vars["revisionId"], revertedBy)
+	if err != nil {
+		if !errors.Is(err, poi.ErrPoiNotFound) && !errors.Is(err, poi.ErrRevisionNotFound) {
+			h.logger.Error("not able to revert place", slog.Any("err", err))
+		}
+		templates.ErrorMessage("Unable to revert the place").Render(r.Context(), w)
+		return
+	}
+
+	w.Header().Set("HX-Redirect", "/wheretoplay/"+url.PathEscape(reverted.SportType)+"/"+reverted.ID)
+}
+
+func (h *WhereToPlayHandler) serveSuggestEditFormHTML(w http.ResponseWriter, r *http.Request) {
+	poi := h.visiblePoi(r)
+	if poi == nil {
+		w.WriteHeader(http.StatusNotFound)
+		templates.NotFoundMessage().Render(r.Context(), w)
+		return
+	}
+
+	templates.SuggestEditForm(*poi).Render(r.Context(), w)
+}
+
+func (h *WhereToPlayHandler) suggestEdit(w http.ResponseWriter, r *http.Request) {
+	if !utils.Logined(r.Context()) {
+		w.Header().Set("HX-Redirect", "/login")
+		return
+	}
+	if !utils.EmailVerified(r.Context()) {
+		templates.ErrorMessage("Please verify your email address before suggesting edits").Render(r.Context(), w)
+		return
+	}
+	existing := h.visiblePoi(r)
+	if existing == nil {
+		templates.ErrorMessage("The place no longer exists").Render(r.Context(), w)
+		return
+	}
+
+	changes, err := parseSuggestedChangesAndValidate(r)
+	if err != nil {
+		templates.ErrorMessage(err.Error()).Render(r.Context(), w)
+		return
+	}
+
+	suggestedBy := r.Context().Value(utils.UserIdKey).(string)
+	if _, err := h.poiService.SuggestEdit(existing.ID, suggestedBy, changes, r.FormValue("comment")); err != nil {
+		if errors.Is(err, poi.ErrEmptySuggestion) {
+			templates.ErrorMessage("Change at least one field").Render(r.Context(), w)
 			return
 		}
+		if errors.Is(err, poi.ErrSuggestRateLimited) {
+			templates.ErrorMessage("You sent too many suggestions, please try again later").Render(r.Context(), w)
+			return
+		}
+		templates.ErrorMessage("Unable to send the suggestion").Render(r.Context(), w)
+		return
+	}
+
+	templates.AccountMessage("Thanks! A moderator will review your suggestion.").Render(r.Context(), w)
+}
+
+func (h *WhereToPlayHandler) uploadPhotos(w http.ResponseWriter, r *http.Request) {
+	if !utils.Logined(r.Context()) {
+		w.Header().Set("HX-Redirect", "/login")
+		return
+	}
+	if !utils.EmailVerified(r.Context()) {
+		templates.ErrorMessage("Please verify your email address before adding photos").Render(r.Context(), w)
+		return
+	}
+	existing := h.visiblePoi(r)
+	if existing == nil {
+		templates.ErrorMessage("The place no longer exists").Render(r.Context(), w)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxPhotosPerUpload*imaging.MaxImageSize+1024*1024)
+	if err := r.ParseMultipartForm(1024 * 1024); err != nil {
+		templates.ErrorMessage("Photos must be less than 15 MB each").Render(r.Context(), w)
+		return
+	}
+	files := r.MultipartForm.File["photos"]
+	if len(files) == 0 || len(files) > maxPhotosPerUpload {
+		templates.ErrorMessage(fmt.Sprintf("Pick 1 to %d photos", maxPhotosPerUpload)).Render(r.Context(), w)
+		return
+	}
+	for _, file := range files {
+		if file.Size > imaging.MaxImageSize {
+			templates.ErrorMessage(file.Filename+" is larger than 15 MB").Render(r.Context(), w)
+			return
+		}
+	}
+
+	uploadedBy := r.Context().Value(utils.UserIdKey).(string)
+	trusted := utils.HasRole(r.Context(), user.TrustedRoles...)
+	for _, file := range files {
+		if err := h.uploadPhoto(r, existing.ID, uploadedBy, file, trusted); err != nil {
+			templates.ErrorMessage(err.Error()).Render(r.Context(), w)
+			return
+		}
+	}
+
+	if !trusted {
+		templates.AccountMessage("Thanks! Your photos will be shown to everyone once a moderator approves them.").Render(r.Context(), w)
+		return
+	}
+	w.Header().Set("HX-Redirect", "/wheretoplay/"+url.PathEscape(existing.SportType)+"/"+existing.ID)
+}
+
+func (h *WhereToPlayHandler) uploadPhoto(r *http.Request, poiId, uploadedBy string, header *multipart.FileHeader, trusted bool) error {
+	file, err := header.Open()
+	if err != nil {
+		return fmt.Errorf("cannot read %s", header.Filename)
+	}
+	defer file.Close()
+
+	renditions, err := h.storeImage(r, "poi/photos/"+poiId+"/"+uuid.New().String(), file)
+	if err != nil {
+		return fmt.Errorf("%s: %w", header.Filename, err)
+	}
+
+	if _, err := h.poiService.AddPhoto(poiId, uploadedBy, renditions, r.FormValue("caption"), trusted); err != nil {
+		imaging.Delete(r.Context(), h.blobStore, renditions)
+		if errors.Is(err, poi.ErrCaptionTooLong) {
+			return err
+		}
+		return fmt.Errorf("cannot add %s", header.Filename)
+	}
+	return nil
+}
+
+// Decodes the uploaded image and stores its renditions under prefix, the
+// returned error can be shown to the user
+func (h *WhereToPlayHandler) storeImage(r *http.Request, prefix string, file io.Reader) (poi.Renditions, error) {
+	data, err := imaging.Read(file)
+	if err != nil {
+		if errors.Is(err, imaging.ErrFileTooLarge) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("cannot read the image")
+	}
+	renditions, err := imaging.Process(data)
+	if err != nil {
+		if errors.Is(err, imaging.ErrUnsupportedImage) || errors.Is(err, imaging.ErrImageTooLarge) {
+			return nil, err
+		}
+		h.logger.Error("cannot process image", slog.Any("err", err))
+		return nil, fmt.Errorf("cannot read the image")
+	}
+	stored, err := imaging.Store(r.Context(), h.blobStore, prefix, renditions)
+	if err != nil {
+		h.logger.Error("cannot upload image", slog.Any("err", err))
+		return nil, fmt.Errorf("cannot upload the image")
+	}
+	return stored, nil
+}
+
+func (h *WhereToPlayHandler) setCoverPhoto(w http.ResponseWriter, r *http.Request) {
+	if !utils.Logined(r.Context()) {
+		w.Header().Set("HX-Redirect", "/login")
+		return
+	}
+
+	vars := mux.Vars(r)
+	userId := r.Context().Value(utils.UserIdKey).(string)
+	moderator := utils.HasRole(r.Context(), user.ModeratorRoles...)
+	updated, err := h.poiService.SetCoverPhoto(vars["placeId"], vars["photoId"], userId, moderator)
+	if err != nil {
+		switch {
+		case errors.Is(err, poi.ErrNotPlaceOwner), errors.Is(err, poi.ErrPhotoNotApproved):
+			templates.ErrorMessage(err.Error()).Render(r.Context(), w)
+		case errors.Is(err, poi.ErrPoiNotFound), errors.Is(err, poi.ErrPhotoNotFound):
+			templates.ErrorMessage("The photo no longer exists").Render(r.Context(), w)
+		default:
+			templates.ErrorMessage("Unable to change the cover, please try again").Render(r.Context(), w)
+		}
+		return
+	}
+
+	w.Header().Set("HX-Redirect", "/wheretoplay/"+url.PathEscape(updated.SportType)+"/"+updated.ID)
+}
+
+func (h *WhereToPlayHandler) placeReviews(w http.ResponseWriter, r *http.Request) {
+	poi := h.visiblePoi(r)
+	if poi == nil {
+		w.WriteHeader(http.StatusNotFound)
+		templates.NotFoundMessage().Render(r.Context(), w)
+		return
+	}
+
+	h.renderReviews(w, r, *poi, "")
+}
+
+// Posts the review of the user, or updates it when the user already reviewed the place
+func (h *WhereToPlayHandler) saveReview(w http.ResponseWriter, r *http.Request) {
+	if !utils.Logined(r.Context()) {
+		w.Header().Set("HX-Redirect", "/login")
+		return
+	}
+	existing := h.visiblePoi(r)
+	if existing == nil {
+		templates.ErrorMessage("The place no longer exists").Render(r.Context(), w)
+		return
+	}
+
+	stars, _ := strconv.Atoi(r.FormValue("stars"))
+	var visitedOn *time.Time
+	if value := r.FormValue("visitedOn"); value != "" {
+		parsed, err := time.Parse("2006-01-02", value)
 		if err != nil {
-			h.logger.Error("Cannot get place details from google api", slog.Any("err", err), slog.Any("details", details))
+			h.renderReviews(w, r, *existing, "Invalid visit date")
+			return
+		}
+		visitedOn = &parsed
+	}
+
+	userId := r.Context().Value(utils.UserIdKey).(string)
+	var err error
+	if mine := h.poiService.GetUserReview(existing.ID, userId); mine != nil {
+		_, err = h.poiService.UpdateReview(existing.ID, mine.ID, userId, stars, r.FormValue("text"), visitedOn)
+	} else {
+		_, err = h.poiService.CreateReview(existing.ID, userId, stars, r.FormValue("text"), visitedOn)
+	}
+	if err != nil {
+		h.renderReviews(w, r, *existing, reviewErrorMessage(err))
+		return
+	}
+
+	// the rating of the place changed
+	if updated := h.poiService.GetPoiById(existing.ID); updated != nil {
+		existing = updated
+	}
+	h.renderReviews(w, r, *existing, "")
+}
+
+func (h *WhereToPlayHandler) deleteReview(w http.ResponseWriter, r *http.Request) {
+	existing := h.visiblePoi(r)
+	if existing == nil {
+		templates.ErrorMessage("The place no longer exists").Render(r.Context(), w)
+		return
+	}
+
+	userId, _ := r.Context().Value(utils.UserIdKey).(string)
+	moderator := utils.HasRole(r.Context(), user.ModeratorRoles...)
+	if err := h.poiService.DeleteReview(existing.ID, mux.Vars(r)["reviewId"], userId, moderator); err != nil {
+		h.renderReviews(w, r, *existing, reviewErrorMessage(err))
+		return
+	}
+
+	if updated := h.poiService.GetPoiById(existing.ID); updated != nil {
+		existing = updated
+	}
+	h.renderReviews(w, r, *existing, "")
+}
+
+func (h *WhereToPlayHandler) renderReviews(w http.ResponseWriter, r *http.Request, p poi.Poi, errorMessage string) {
+	var mine *poi.Review
+	if userId, ok := r.Context().Value(utils.UserIdKey).(string); ok {
+		mine = h.poiService.GetUserReview(p.ID, userId)
+	}
+
+	templates.PlaceReviews(p, h.poiService.GetReviews(p.ID), mine, errorMessage).Render(r.Context(), w)
+}
+
+func reviewErrorMessage(err error) string {
+	switch {
+	case errors.Is(err, poi.ErrInvalidStars):
+		return "Pick 1 to 5 stars"
+	case errors.Is(err, poi.ErrReviewTooLong), errors.Is(err, poi.ErrVisitInFuture):
+		return err.Error()
+	case errors.Is(err, poi.ErrReviewNotFound):
+		return "The review no longer exists"
+	case errors.Is(err, poi.ErrNotReviewAuthor):
+		return "You can only change your own review"
+	case errors.Is(err, poi.ErrAlreadyReviewed):
+		return "You already reviewed this place, edit your review instead"
+	default:
+		return "Unable to save the review, please try again"
+	}
+}
+
+// Fields left empty are not changed, except the website
+func parseSuggestedChangesAndValidate(r *http.Request) (poi.SuggestedChanges, error) {
+	changes := poi.SuggestedChanges{
+		Closed: r.FormValue("closed") == "true",
+	}
+	if name := strings.TrimSpace(r.FormValue("name")); name != "" {
+		if len(name) < 3 || len(name) > 100 {
+			return changes, fmt.Errorf("name must be 3 to 100 characters")
+		}
+		changes.Name = &name
+	}
+	if address := strings.TrimSpace(r.FormValue("address")); address != "" {
+		changes.Address = &address
+	}
+	// the form is filled with the current values, so a cleared website removes it
+	website := strings.TrimSpace(r.FormValue("website"))
+	changes.Website = &website
+	if description := strings.TrimSpace(r.FormValue("description")); description != "" {
+		if len(description) < 50 || len(description) > 8000 {
+			return changes, fmt.Errorf("description must be 50 to 8000 characters")
 		}
+		changes.Description = &description
 	}
-	templates.Layout(templates.NotFoundMessage()).Render(r.Context(), w)
+	if len(r.FormValue("comment")) > 1000 {
+		return changes, fmt.Errorf("comment must be at most 1000 characters")
+	}
+
+	return changes, nil
+}
+
+// Pending and rejected places are only shown to their submitter and moderators
+func (h *WhereToPlayHandler) visiblePoi(r *http.Request) *poi.Poi {
+	poi := h.poiService.GetPoiById(mux.Vars(r)["placeId"])
+	viewerId, _ := r.Context().Value(utils.UserIdKey).(string)
+	if poi == nil || !poi.VisibleTo(viewerId, utils.HasRole(r.Context(), user.ModeratorRoles...)) {
+		return nil
+	}
+	return poi
 }
 
 func getGooglePlaceDetails(googlePlaceId, apiKey string) (*types.GooglePlaceResponse, error) {
@@ -113,7 +522,7 @@ func getGooglePlaceDetails(googlePlaceId, apiKey string) (*types.GooglePlaceResp
 }
 
 func (h *WhereToPlayHandler) serveWhereToPlayPageHTML(w http.ResponseWriter, r *http.Request) {
-	content := templates.WhereToPlayPage()
+	content := templates.WhereToPlayPage(h.sportService.GetCatalogue())
 	err := templates.MapLayout(content).Render(r.Context(), w)
 
 	if err != nil {
@@ -125,28 +534,113 @@ func (h *WhereToPlayHandler) serveWhereToPlayPageHTML(w http.ResponseWriter, r *
 func (h *WhereToPlayHandler) searchWhereToPlay(w http.ResponseWriter, r *http.Request) {
 	cityPlaceId := r.FormValue(cityPlaceIdParam)
 	sport := r.FormValue(sportParam)
+	sortBy := poi.ParsePoiSort(r.FormValue(sortParam))
 
-	pageSize := 15
+	pageSize := defaultPageSize
 	cursor := r.URL.Query().Get(cursorParam)
-	pageSizeParam := r.URL.Query().Get(pageSizeParam)
-	if pageSizeParam != "" {
-		pageSize, _ = strconv.Atoi(pageSizeParam)
+	// the services slice their results by the page size
+	if parsed, err := strconv.Atoi(r.URL.Query().Get(pageSizeParam)); err == nil {
+		pageSize = min(max(parsed, 1), maxPageSize)
+	}
+
+	if r.FormValue(latParam) != "" || r.FormValue(lngParam) != "" {
+		h.searchWhereToPlayNearby(w, r, sport, sortBy, cursor, pageSize)
+		return
+	}
+
+	// a city typed without picking an autocomplete suggestion is looked up by
+	// name, cities are only resolved through Google when places are added
+	if cityName := strings.TrimSpace(r.FormValue(cityParam)); cityPlaceId == "" && cityName != "" {
+		if cities := h.cityService.SearchCities(cityName, 1); len(cities) > 0 {
+			cityPlaceId = cities[0].PlaceId
+		}
+	}
+
+	if query := strings.TrimSpace(r.FormValue(queryParam)); query != "" {
+		h.fullTextSearchWhereToPlay(w, r, query, cityPlaceId, sport, sortBy, cursor, pageSize)
+		return
 	}
 
 	if cityPlaceId == "" || sport == "" {
-		templates.SearchError("Pick a sport and city!").Render(r.Context(), w)
+		templates.SearchError("Pick a sport and city, or type what you are looking for!").Render(r.Context(), w)
+		return
+	}
+
+	viewerId, _ := r.Context().Value(utils.UserIdKey).(string)
+	pois := h.poiService.SearchPois(cityPlaceId, sport, viewerId, sortBy, cursor, pageSize)
+
+	nextPageUrl := ""
+	if pois.Cursor != "" {
+		nextPageUrl = fmt.Sprintf("/wheretoplay/search?sport=%s&cityPlaceId=%s&sort=%s&pageSize=%d&cursor=%s",
+			url.QueryEscape(sport), url.QueryEscape(cityPlaceId), sortBy, pageSize, url.QueryEscape(pois.Cursor))
+	}
+
+	w.WriteHeader(http.StatusOK)
+	templates.SearchResult(pois, nextPageUrl).Render(r.Context(), w)
+}
+
+func (h *WhereToPlayHandler) fullTextSearchWhereToPlay(w http.ResponseWriter, r *http.Request, query, cityPlaceId, sport string, sortBy poi.PoiSort, cursor string, pageSize int) {
+	viewerId, _ := r.Context().Value(utils.UserIdKey).(string)
+	filters := poi.SearchFilters{CityId: cityPlaceId, Sport: sport, ViewerId: viewerId, Sort: sortBy}
+	pois := h.poiService.FullTextSearch(query, filters, cursor, pageSize)
+
+	nextPageUrl := ""
+	if pois.Cursor != "" {
+		nextPageUrl = fmt.Sprintf("/wheretoplay/search?q=%s&sport=%s&cityPlaceId=%s&sort=%s&pageSize=%d&cursor=%s",
+			url.QueryEscape(query), url.QueryEscape(sport), url.QueryEscape(cityPlaceId), sortBy, pageSize, url.QueryEscape(pois.Cursor))
+	}
+
+	w.WriteHeader(http.StatusOK)
+	templates.SearchResult(pois, nextPageUrl).Render(r.Context(), w)
+}
+
+func (h *WhereToPlayHandler) searchWhereToPlayNearby(w http.ResponseWriter, r *http.Request, sport string, sortBy poi.PoiSort, cursor string, pageSize int) {
+	lat, latErr := strconv.ParseFloat(r.FormValue(latParam), 64)
+	lng, lngErr := strconv.ParseFloat(r.FormValue(lngParam), 64)
+	if latErr != nil || lngErr != nil || !validCoordinates(lat, lng) {
+		templates.SearchError("Invalid location!").Render(r.Context(), w)
 		return
 	}
 
-	pois := h.poiService.SearchPois(cityPlaceId, sport, cursor, pageSize)
+	radiusKm := defaultRadiusKm
+	if radiusKmValue := r.FormValue(radiusKmParam); radiusKmValue != "" {
+		parsed, err := strconv.ParseFloat(radiusKmValue, 64)
+		if err != nil || parsed <= 0 {
+			templates.SearchError("Invalid search radius!").Render(r.Context(), w)
+			return
+		}
+		radiusKm = math.Min(parsed, maxRadiusKm)
+	}
+
+	viewerId, _ := r.Context().Value(utils.UserIdKey).(string)
+	pois := h.poiService.SearchPoisNearby(lat, lng, radiusKm, sport, viewerId, sortBy, cursor, pageSize)
+
+	nextPageUrl := ""
+	if pois.Cursor != "" {
+		nextPageUrl = fmt.Sprintf("/wheretoplay/search?lat=%f&lng=%f&radiusKm=%g&sport=%s&sort=%s&pageSize=%d&cursor=%s",
+			lat, lng, radiusKm, url.QueryEscape(sport), sortBy, pageSize, url.QueryEscape(pois.Cursor))
+	}
 
 	w.WriteHeader(http.StatusOK)
-	templates.SearchResult(pois, sport, cityPlaceId, pageSize).Render(r.Context(), w)
+	templates.SearchResult(pois, nextPageUrl).Render(r.Context(), w)
+}
+
+// ParseFloat also accepts NaN and Inf, which must not reach the distance queries
+func validCoordinates(latitude, longitude float64) bool {
+	return !math.IsNaN(latitude) && !math.IsNaN(longitude) &&
+		latitude >= -90 && latitude <= 90 && longitude >= -180 && longitude <= 180
 }
 
 func (h *WhereToPlayHandler) serveCreateNewPlacePageHTML(w http.ResponseWriter, r *http.Request) {
 	if utils.Logined(r.Context()) {
-		content := templates.CreateNewPlace()
+		if !utils.EmailVerified(r.Context()) {
+			if err := templates.Layout(templates.EmailNotVerified()).Render(r.Context(), w); err != nil {
+				http.Error(w, "Error rendering page", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		content := templates.CreateNewPlace(h.sportService.GetCatalogue())
 		err := templates.MapLayout(content).Render(r.Context(), w)
 
 		if err != nil {
@@ -158,6 +652,12 @@ func (h *WhereToPlayHandler) serveCreateNewPlacePageHTML(w http.ResponseWriter,
 }
 
 func (h *WhereToPlayHandler) createNewPlace(w http.ResponseWriter, r *http.Request) {
+	if !utils.EmailVerified(r.Context()) {
+		templates.ErrorMessage("Please verify your email address before adding places").Render(r.Context(), w)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, imaging.MaxImageSize+1024*1024)
 	input, err := h.parseCreateNewPlaceFormInputAndValidate(r)
 	if err != nil {
 		templates.ErrorMessage(err.Error()).Render(r.Context(), w)
@@ -165,57 +665,114 @@ func (h *WhereToPlayHandler) createNewPlace(w http.ResponseWriter, r *http.Reque
 	}
 	defer input.Thumbnail.Close()
 
-	objectName := "poi/thumbnails/" + uuid.New().String() + "/" + input.ThumbnailFilename
-	wc := h.cloudStorage.Bucket(h.bucket).
-		Object(objectName).
-		NewWriter(r.Context())
-	defer wc.Close()
+	if r.FormValue("notDuplicate") != "true" {
+		if duplicates := h.findDuplicates(r, input.Name, input.Address, input.CityId, input.Latitude, input.Longitude); len(duplicates) > 0 {
+			templates.DuplicateWarning(duplicates, true).Render(r.Context(), w)
+			return
+		}
+	}
 
-	if _, err := io.Copy(wc, input.Thumbnail); err != nil {
-		templates.ErrorMessage("cannot upload thumbnail").Render(r.Context(), w)
+	thumbnail, err := h.storeImage(r, "poi/thumbnails/"+uuid.New().String(), input.Thumbnail)
+	if err != nil {
+		templates.ErrorMessage("thumbnail: "+err.Error()).Render(r.Context(), w)
 		return
 	}
 
-	thumbnailUrl := fmt.Sprintf("https://storage.googleapis.com/%s/%s", h.bucket, objectName)
-
 	createdBy := r.Context().Value(utils.UserIdKey).(string)
-	h.poiService.CreatePoi(
+	newPoi, err := h.poiService.CreatePoi(
 		createdBy,
 		input.Name,
 		input.Description,
 		input.Address,
 		input.CityId,
 		nil,
+		input.Latitude,
+		input.Longitude,
 		input.Website,
-		input.Sport,
-		thumbnailUrl,
+		input.Sports,
+		thumbnail,
+		nil,
 		"",
+		utils.HasRole(r.Context(), user.TrustedRoles...),
 	)
+	if err != nil {
+		imaging.Delete(r.Context(), h.blobStore, thumbnail)
+		templates.ErrorMessage("cannot create the place").Render(r.Context(), w)
+		return
+	}
+
+	if newPoi.Status == poi.PoiPending {
+		templates.AccountMessage("Thanks! Your place will be visible to everyone once a moderator approves it.").Render(r.Context(), w)
+		return
+	}
 	w.Header().Set("HX-Redirect", "/wheretoplay")
 	w.WriteHeader(http.StatusSeeOther)
 }
 
+// Warns while the form is filled in when the place seems to exist already
+func (h *WhereToPlayHandler) checkDuplicates(w http.ResponseWriter, r *http.Request) {
+	var latitude, longitude *float64
+	lat, latErr := strconv.ParseFloat(r.FormValue("latitude"), 64)
+	lng, lngErr := strconv.ParseFloat(r.FormValue("longitude"), 64)
+	if latErr == nil && lngErr == nil && validCoordinates(lat, lng) {
+		latitude, longitude = &lat, &lng
+	}
+
+	name := r.FormValue("name")
+	if len(name) < 3 {
+		return
+	}
+	duplicates := h.findDuplicates(r, name, r.FormValue("address"), r.FormValue("cityPlaceId"), latitude, longitude)
+	templates.DuplicateWarning(duplicates, false).Render(r.Context(), w)
+}
+
+func (h *WhereToPlayHandler) findDuplicates(r *http.Request, name, address, cityId string, latitude, longitude *float64) []poi.DuplicateCandidate {
+	viewerId, _ := r.Context().Value(utils.UserIdKey).(string)
+	moderator := utils.HasRole(r.Context(), user.ModeratorRoles...)
+
+	var visible []poi.DuplicateCandidate
+	for _, candidate := range h.poiService.FindDuplicates(name, address, cityId, latitude, longitude, "") {
+		if candidate.Poi.VisibleTo(viewerId, moderator) {
+			visible = append(visible, candidate)
+		}
+	}
+	return visible
+}
+
 func (h *WhereToPlayHandler) parseCreateNewPlaceFormInputAndValidate(r *http.Request) (*templates.CreateNewPlaceFormInput, error) {
 	if err := r.ParseMultipartForm(1024 * 1024); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return nil, fmt.Errorf("thumbnail must be less than 15 MB")
+		}
 		return nil, err
 	}
 	thumbnail, thumbnailHeader, err := r.FormFile("thumbnail")
 	if err != nil {
 		return nil, err
 	}
-	if thumbnailHeader.Size > maxThumbnailSize {
-		return nil, fmt.Errorf("thumbnail must be less than 100 KB")
+	if thumbnailHeader.Size > imaging.MaxImageSize {
+		return nil, fmt.Errorf("thumbnail must be less than 15 MB")
 	}
 
 	input := templates.CreateNewPlaceFormInput{
-		Name:              r.FormValue("name"),
-		Description:       r.FormValue("description"),
-		Address:           r.FormValue("address"),
-		CityId:            r.FormValue("cityPlaceId"),
-		Website:           r.FormValue("website"),
-		Sport:             r.FormValue("sport"),
-		Thumbnail:         thumbnail,
-		ThumbnailFilename: thumbnailHeader.Filename,
+		Name:        r.FormValue("name"),
+		Description: r.FormValue("description"),
+		Address:     r.FormValue("address"),
+		CityId:      r.FormValue("cityPlaceId"),
+		Website:     r.FormValue("website"),
+		Sports:      r.MultipartForm.Value["sport"],
+		Thumbnail:   thumbnail,
+	}
+
+	if latValue, lngValue := r.FormValue("latitude"), r.FormValue("longitude"); latValue != "" || lngValue != "" {
+		latitude, latErr := strconv.ParseFloat(latValue, 64)
+		longitude, lngErr := strconv.ParseFloat(lngValue, 64)
+		if latErr != nil || lngErr != nil || !validCoordinates(latitude, longitude) {
+			return nil, fmt.Errorf("latitude must be between -90 and 90, longitude between -180 and 180")
+		}
+		input.Latitude = &latitude
+		input.Longitude = &longitude
 	}
 
 	if len(input.Name) < 3 || len(input.Name) > 100 {
@@ -224,6 +781,12 @@ func (h *WhereToPlayHandler) parseCreateNewPlaceFormInputAndValidate(r *http.Req
 	if len(input.Description) < 50 || len(input.Description) > 8000 {
 		return nil, fmt.Errorf("description must be 550 to 8000 characters")
 	}
+	if _, err := h.sportService.ResolveSports(input.Sports); err != nil {
+		if errors.Is(err, sport.ErrNoSport) {
+			return nil, fmt.Errorf("pick at least one sport")
+		}
+		return nil, err
+	}
 
 	return &input, nil
 }
@@ -0,0 +1,21 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+var ErrBlobNotFound = errors.New("blob not found")
+
+// Object storage for uploaded files such as place thumbnails
+type BlobStore interface {
+	Put(ctx context.Context, name string, content io.Reader, contentType string) error
+	Get(ctx context.Context, name string) (io.ReadCloser, error)
+	Delete(ctx context.Context, name string) error
+	// URL anyone can read the object from
+	PublicURL(name string) string
+	// URL granting temporary read access to the object
+	SignedURL(name string, expires time.Duration) (string, error)
+}
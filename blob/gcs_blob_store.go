@@ -0,0 +1,65 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"cloud.google.com/go/storage"
+)
+
+type GcsBlobStore struct {
+	client *storage.Client
+	bucket string
+}
+
+func NewGcsBlobStore(client *storage.Client, bucket string) *GcsBlobStore {
+	return &GcsBlobStore{
+		client: client,
+		bucket: bucket,
+	}
+}
+
+func (s *GcsBlobStore) Put(ctx context.Context, name string, content io.Reader, contentType string) error {
+	wc := s.client.Bucket(s.bucket).Object(name).NewWriter(ctx)
+	wc.ContentType = contentType
+
+	if _, err := io.Copy(wc, content); err != nil {
+		wc.Close()
+		return fmt.Errorf("error uploading %s: %v", name, err)
+	}
+	if err := wc.Close(); err != nil {
+		return fmt.Errorf("error uploading %s: %v", name, err)
+	}
+	return nil
+}
+
+func (s *GcsBlobStore) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	reader, err := s.client.Bucket(s.bucket).Object(name).NewReader(ctx)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return nil, ErrBlobNotFound
+	}
+	return reader, err
+}
+
+func (s *GcsBlobStore) Delete(ctx context.Context, name string) error {
+	err := s.client.Bucket(s.bucket).Object(name).Delete(ctx)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return ErrBlobNotFound
+	}
+	return err
+}
+
+func (s *GcsBlobStore) PublicURL(name string) string {
+	return fmt.Sprintf("https://storage.googleapis.com/%s/%s", s.bucket, name)
+}
+
+func (s *GcsBlobStore) SignedURL(name string, expires time.Duration) (string, error) {
+	return s.client.Bucket(s.bucket).SignedURL(name, &storage.SignedURLOptions{
+		Method:  "GET",
+		Expires: time.Now().Add(expires),
+		Scheme:  storage.SigningSchemeV4,
+	})
+}
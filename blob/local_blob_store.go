@@ -0,0 +1,160 @@
+package blob
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Stores blobs on the local disk, for development and tests without a GCP bucket.
+// Objects are served by Handler under urlPrefix.
+type LocalBlobStore struct {
+	dir        string
+	urlPrefix  string
+	signingKey []byte
+}
+
+func NewLocalBlobStore(dir, urlPrefix string, signingKey []byte) (*LocalBlobStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("error creating blob directory: %v", err)
+	}
+
+	return &LocalBlobStore{
+		dir:        dir,
+		urlPrefix:  strings.TrimSuffix(urlPrefix, "/"),
+		signingKey: signingKey,
+	}, nil
+}
+
+func (s *LocalBlobStore) Put(ctx context.Context, name string, content io.Reader, contentType string) error {
+	path, err := s.path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("error uploading %s: %v", name, err)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("error uploading %s: %v", name, err)
+	}
+	if _, err := io.Copy(file, content); err != nil {
+		file.Close()
+		return fmt.Errorf("error uploading %s: %v", name, err)
+	}
+	return file.Close()
+}
+
+func (s *LocalBlobStore) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	path, err := s.path(name)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrBlobNotFound
+	}
+	return file, err
+}
+
+func (s *LocalBlobStore) Delete(ctx context.Context, name string) error {
+	path, err := s.path(name)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrBlobNotFound
+	}
+	return err
+}
+
+func (s *LocalBlobStore) PublicURL(name string) string {
+	return s.urlPrefix + "/" + escapePath(name)
+}
+
+func (s *LocalBlobStore) SignedURL(name string, expires time.Duration) (string, error) {
+	if _, err := s.path(name); err != nil {
+		return "", err
+	}
+
+	expiresAt := strconv.FormatInt(time.Now().Add(expires).Unix(), 10)
+	query := url.Values{
+		"expires":   {expiresAt},
+		"signature": {s.sign(name, expiresAt)},
+	}
+	return s.PublicURL(name) + "?" + query.Encode(), nil
+}
+
+// Serves the blobs under the url prefix. Signed urls are rejected once expired.
+func (s *LocalBlobStore) Handler() http.Handler {
+	return http.StripPrefix(s.urlPrefix+"/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.URL.Path
+
+		if signature := r.URL.Query().Get("signature"); signature != "" {
+			expiresAt := r.URL.Query().Get("expires")
+			expires, err := strconv.ParseInt(expiresAt, 10, 64)
+			if err != nil || time.Now().Unix() > expires ||
+				!hmac.Equal([]byte(signature), []byte(s.sign(name, expiresAt))) {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+		}
+
+		path, err := s.path(name)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		defer file.Close()
+
+		info, err := file.Stat()
+		if err != nil || info.IsDir() {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeContent(w, r, filepath.Base(path), info.ModTime(), file)
+	}))
+}
+
+// Resolves the object name inside the blob directory, rejecting names escaping it
+func (s *LocalBlobStore) path(name string) (string, error) {
+	path := filepath.Join(s.dir, filepath.FromSlash(name))
+	if name == "" || !strings.HasPrefix(path, filepath.Clean(s.dir)+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid blob name %q", name)
+	}
+	return path, nil
+}
+
+func (s *LocalBlobStore) sign(name, expiresAt string) string {
+	mac := hmac.New(sha256.New, s.signingKey)
+	mac.Write([]byte(name + "\n" + expiresAt))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func escapePath(name string) string {
+	segments := strings.Split(name, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
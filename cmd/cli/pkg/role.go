@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/sportspazz/configs"
+	"github.com/sportspazz/service/user"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+var roleCmd = &cobra.Command{
+	Use:   "role",
+	Short: "Manage user roles, connects to the database configured in the environment",
+}
+
+var grantRoleCmd = &cobra.Command{
+	Use:   "grant <user_id_or_email> <role>",
+	Short: "Grant a role to a user",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		store, u, role := resolveRoleArgs(args)
+
+		if err := store.GrantRole(u.ID, role, nil); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Granted %s to %s\n", role, u.Email)
+	},
+}
+
+var revokeRoleCmd = &cobra.Command{
+	Use:   "revoke <user_id_or_email> <role>",
+	Short: "Revoke a role from a user",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		store, u, role := resolveRoleArgs(args)
+
+		revoked, err := store.RevokeRole(u.ID, role)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if !revoked {
+			fmt.Printf("%s does not have %s\n", u.Email, role)
+			return
+		}
+		fmt.Printf("Revoked %s from %s\n", role, u.Email)
+	},
+}
+
+func init() {
+	roleCmd.AddCommand(grantRoleCmd)
+	roleCmd.AddCommand(revokeRoleCmd)
+}
+
+func resolveRoleArgs(args []string) (*user.UserStore, *user.User, user.Role) {
+	role, err := user.ParseRole(args[1])
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	db, err := gorm.Open(postgres.Open(configs.Envs.DSN()), &gorm.Config{})
+	if err != nil {
+		log.Fatalf("cannot connect to database: %v", err)
+	}
+	store := user.NewUserStore(db, slog.New(slog.NewTextHandler(os.Stderr, nil)))
+
+	var u *user.User
+	if strings.Contains(args[0], "@") {
+		u = store.GetUserByEmail(args[0])
+	} else {
+		u = store.GetUserById(args[0])
+	}
+	if u == nil {
+		log.Fatalf("user %s not found", args[0])
+	}
+
+	return store, u, role
+}
@@ -21,4 +21,5 @@ func Execute() {
 
 func init() {
 	rootCmd.AddCommand(preSeedPoiCmd)
+	rootCmd.AddCommand(roleCmd)
 }
@@ -124,7 +124,7 @@ func printPlaceDetail(place Place) {
 			review := placeDetails.Reviews[0]
 			fmt.Printf("Review by %s: %s (Rating: %d)\n", review.AuthorName, review.Text, review.Rating)
 		}
-		fmt.Println("\n")
+		fmt.Println()
 	}
 }
 
@@ -154,10 +154,9 @@ func searchAllPlaces(city, sport string, numPages int) ([]POI, error) {
 
 	var pois []POI
 	for _, place := range allPlaces {
-		thumbnailURL := ""
+		var thumbnail Photo
 		if len(place.Photos) > 0 {
-			photo := place.Photos[0]
-			thumbnailURL = fmt.Sprintf("https://maps.googleapis.com/maps/api/place/photo?maxwidth=400&photoreference=%s&key=%s", photo.PhotoReference, apiKey)
+			thumbnail = place.Photos[0]
 		}
 		description := ""
 		placeDetails, err := getPlaceDetails(apiKey, place.PlaceID)
@@ -172,13 +171,16 @@ func searchAllPlaces(city, sport string, numPages int) ([]POI, error) {
 		}
 
 		if err := createPOI(POI{
-			Name:         place.Name,
-			Address:      place.Address,
-			CityID:       cityPlaceId,
-			GooglePlaceId: placeDetails.PlaceID,
-			SportType:    sport,
-			ThumbnailURL: thumbnailURL,
-			Description:  description,
+			Name:                    place.Name,
+			Address:                 place.Address,
+			CityID:                  cityPlaceId,
+			GooglePlaceId:           placeDetails.PlaceID,
+			Latitude:                place.Geometry.Location.Lat,
+			Longitude:               place.Geometry.Location.Lng,
+			SportTypes:              []string{sport},
+			ThumbnailPhotoReference: thumbnail.PhotoReference,
+			ThumbnailAttributions:   thumbnail.HTMLAttributions,
+			Description:             description,
 		}); err != nil {
 			fmt.Println(err)
 			break
@@ -292,6 +294,11 @@ func createPOI(poi POI) error {
 	}
 	defer resp.Body.Close()
 
+	// the place exists already and cannot get the sport added by this user
+	if resp.StatusCode == http.StatusConflict {
+		fmt.Printf("Skipping %s, it already exists\n", poi.Name)
+		return nil
+	}
 	if resp.StatusCode != http.StatusOK {
 		body, err := io.ReadAll(resp.Body)
 		if err != nil {
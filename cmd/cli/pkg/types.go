@@ -99,11 +99,15 @@ type Review struct {
 }
 
 type POI struct {
-	Name          string `json:"name"`
-	Address       string `json:"address"`
-	CityID        string `json:"city_id"`
-	GooglePlaceId string `json:"google_place_id"`
-	SportType     string `json:"sport_type"`
-	ThumbnailURL  string `json:"thumbnail_url"`
-	Description   string `json:"description"`
+	Name          string   `json:"name"`
+	Address       string   `json:"address"`
+	CityID        string   `json:"city_id"`
+	GooglePlaceId string   `json:"google_place_id"`
+	Latitude      float64  `json:"latitude"`
+	Longitude     float64  `json:"longitude"`
+	SportTypes    []string `json:"sport_types"`
+	// the server fetches the photo with its own key and shows the attributions
+	ThumbnailPhotoReference string   `json:"thumbnail_photo_reference,omitempty"`
+	ThumbnailAttributions   []string `json:"thumbnail_attributions,omitempty"`
+	Description             string   `json:"description"`
 }
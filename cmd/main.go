@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/rsa"
 	"fmt"
 	"log/slog"
 	"os"
@@ -10,12 +12,18 @@ import (
 
 	"cloud.google.com/go/storage"
 	firebase "firebase.google.com/go/v4"
+	"github.com/dgrijalva/jwt-go"
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 	"github.com/sportspazz/api/client"
+	"github.com/sportspazz/blob"
 	"github.com/sportspazz/cmd/server"
 	"github.com/sportspazz/configs"
+	"github.com/sportspazz/identity"
+	"github.com/sportspazz/mail"
+	"github.com/sportspazz/service/poi"
+	"github.com/sportspazz/service/user"
 	"google.golang.org/api/option"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -24,13 +32,7 @@ import (
 func main() {
 	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
 
-	dsn := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable&TimeZone=UTC",
-		configs.Envs.DBUser,
-		configs.Envs.DBPassword,
-		configs.Envs.DBHost,
-		configs.Envs.DBPort,
-		configs.Envs.DBName,
-	)
+	dsn := configs.Envs.DSN()
 
 	db, _ := gorm.Open(postgres.Open(dsn), &gorm.Config{})
 
@@ -50,32 +52,40 @@ func main() {
 		os.Exit(1)
 	}
 
-	ctx := context.Background()
-	firebaseApp, err := firebase.NewApp(ctx, nil, option.WithCredentialsFile(configs.Envs.GCPApiKey))
+	grantAdminRoles(user.NewUserStore(db, logger), configs.Envs.AdminUserIds, logger)
+
+	// cancelled on shutdown, which stops the background workers
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	identityProvider, err := newIdentityProvider(ctx, configs.Envs, db, logger)
 	if err != nil {
-		logger.Error("error initializing firebase app", slog.Any("err", err))
+		logger.Error("error initializing identity provider", slog.Any("err", err))
 		os.Exit(1)
 	}
-	firebaseRest, err := client.NewFirebaseClient(configs.Envs.GCPApiKey, logger)
+	logger.Info("using identity provider", slog.String("type", configs.Envs.IdentityProvider))
+	blobStore, err := newBlobStore(ctx, configs.Envs)
 	if err != nil {
-		logger.Error("error initializing firebase client", slog.Any("err", err))
+		logger.Error("error initializing blob store", slog.Any("err", err))
 		os.Exit(1)
 	}
-	storageClient, err := storage.NewClient(ctx, option.WithCredentialsFile(configs.Envs.GCPApiKey))
+	logger.Info("using blob store", slog.String("type", configs.Envs.BlobStore))
+	mailSender, err := newMailSender(configs.Envs, logger)
 	if err != nil {
-		logger.Error("error initializing cloud storage", slog.Any("err", err))
+		logger.Error("error initializing mail sender", slog.Any("err", err))
 		os.Exit(1)
 	}
-
-	killSig := make(chan os.Signal, 1)
-	signal.Notify(killSig, os.Interrupt, syscall.SIGTERM)
+	logger.Info("using mail sender", slog.String("type", configs.Envs.MailSender))
+	outbox := mail.NewOutbox(mail.NewOutboxStore(db, logger), mailSender, logger)
+	go outbox.Run(ctx)
+	thumbnailImporter := poi.NewThumbnailImporter(poi.NewPoiStore(db, logger), blobStore, configs.Envs.GoogleMapApiKey, logger)
+	go thumbnailImporter.Run(ctx)
 
 	go func() {
 		server := server.NewServer(
 			db,
-			firebaseApp,
-			firebaseRest,
-			storageClient,
+			identityProvider,
+			blobStore,
+			outbox,
 			configs.Envs)
 
 		if err := server.Run(); err != nil {
@@ -84,6 +94,91 @@ func main() {
 	}()
 
 	logger.Info("Server started", slog.String("port", configs.Envs.Port))
-	<-killSig
+	<-ctx.Done()
+	logger.Info("Shutting down")
+}
+
+// Admins used to be listed in ADMIN_USER_IDS, they are granted the admin role
+// so they keep their access once roles are read from user_roles
+func grantAdminRoles(store *user.UserStore, adminUserIds []string, logger *slog.Logger) {
+	for _, userId := range adminUserIds {
+		if err := store.GrantRole(userId, user.RoleAdmin, nil); err != nil {
+			logger.Error("not able to grant admin role", slog.String("userId", userId), slog.Any("err", err))
+			continue
+		}
+		logger.Info("granted admin role from ADMIN_USER_IDS", slog.String("userId", userId))
+	}
+}
+
+func newBlobStore(ctx context.Context, config configs.Config) (blob.BlobStore, error) {
+	switch config.BlobStore {
+	case "local":
+		signingKey := []byte(config.BlobSigningKey)
+		if len(signingKey) == 0 {
+			signingKey = make([]byte, 32)
+			if _, err := rand.Read(signingKey); err != nil {
+				return nil, err
+			}
+		}
+		return blob.NewLocalBlobStore(config.LocalBlobDir, server.LocalBlobUrlPrefix, signingKey)
+	case "gcs":
+		storageClient, err := storage.NewClient(ctx, option.WithCredentialsFile(config.GCPApiKey))
+		if err != nil {
+			return nil, err
+		}
+		return blob.NewGcsBlobStore(storageClient, config.CloudStorageBucket), nil
+	default:
+		return nil, fmt.Errorf("unknown blob store %q, expecting gcs or local", config.BlobStore)
+	}
+}
+
+func newIdentityProvider(ctx context.Context, config configs.Config, db *gorm.DB, logger *slog.Logger) (identity.IdentityProvider, error) {
+	switch config.IdentityProvider {
+	case "local":
+		privateKey, err := loadLocalAuthKey(config.LocalAuthKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		return identity.NewLocalIdentityProvider(identity.NewLocalIdentityStore(db, logger), privateKey, logger), nil
+	case "firebase":
+		firebaseApp, err := firebase.NewApp(ctx, nil, option.WithCredentialsFile(config.GCPApiKey))
+		if err != nil {
+			return nil, fmt.Errorf("error initializing firebase app: %v", err)
+		}
+		firebaseAuthClient, err := firebaseApp.Auth(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error initializing firebase admin client: %v", err)
+		}
+		firebaseClient, err := client.NewFirebaseClient(config.GCPApiKey, config.FirebaseWebApiKey, logger)
+		if err != nil {
+			return nil, fmt.Errorf("error initializing firebase client: %v", err)
+		}
+		return identity.NewFirebaseIdentityProvider(firebaseClient, firebaseAuthClient), nil
+	default:
+		return nil, fmt.Errorf("unknown identity provider %q, expecting firebase or local", config.IdentityProvider)
+	}
+}
 
+// Without a key file a new key is generated, invalidating tokens issued before a restart
+func loadLocalAuthKey(keyFile string) (*rsa.PrivateKey, error) {
+	if keyFile == "" {
+		return rsa.GenerateKey(rand.Reader, 2048)
+	}
+
+	pem, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading local auth key: %v", err)
+	}
+	return jwt.ParseRSAPrivateKeyFromPEM(pem)
+}
+
+func newMailSender(config configs.Config, logger *slog.Logger) (mail.MailSender, error) {
+	switch config.MailSender {
+	case "log":
+		return mail.NewLogMailSender(config.MailDir, config.MailFrom, logger)
+	case "smtp":
+		return mail.NewSmtpMailSender(config.SmtpHost, config.SmtpPort, config.SmtpUsername, config.SmtpPassword, config.MailFrom), nil
+	default:
+		return nil, fmt.Errorf("unknown mail sender %q, expecting log or smtp", config.MailSender)
+	}
 }
@@ -1,55 +1,62 @@
 package server
 
 import (
-	"context"
+	"expvar"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
 
-	"cloud.google.com/go/storage"
-	firebase "firebase.google.com/go/v4"
 	"github.com/gorilla/mux"
 	"github.com/sportspazz/api/client"
 	rest_api "github.com/sportspazz/api/rest"
 	web "github.com/sportspazz/api/web"
+	"github.com/sportspazz/blob"
 	"github.com/sportspazz/configs"
+	"github.com/sportspazz/identity"
+	"github.com/sportspazz/mail"
 	"github.com/sportspazz/middleware"
+	"github.com/sportspazz/service/city"
 	"github.com/sportspazz/service/poi"
+	"github.com/sportspazz/service/session"
+	"github.com/sportspazz/service/sport"
 	"github.com/sportspazz/service/user"
 	"github.com/sportspazz/static"
 	"gorm.io/gorm"
 )
 
+// Where the local blob store serves its files from
+const LocalBlobUrlPrefix = "/blobs"
+
 type Server struct {
-	port            string
-	db              *gorm.DB
-	firebaseApp     *firebase.App
-	firebaseClient  *client.FirebaseClient
-	storageClient   *storage.Client
-	bucket          string
-	googleMapApiKey string
-	certFile        string
-	keyFile         string
+	port             string
+	db               *gorm.DB
+	identityProvider identity.IdentityProvider
+	blobStore        blob.BlobStore
+	mailSender       mail.MailSender
+	baseUrl          string
+	googleMapApiKey  string
+	certFile         string
+	keyFile          string
 }
 
 func NewServer(
 	db *gorm.DB,
-	firebaseApp *firebase.App,
-	firebaseClient *client.FirebaseClient,
-	storageClient *storage.Client,
+	identityProvider identity.IdentityProvider,
+	blobStore blob.BlobStore,
+	mailSender mail.MailSender,
 	configs configs.Config) *Server {
 
 	return &Server{
-		port:            configs.Port,
-		db:              db,
-		firebaseApp:     firebaseApp,
-		firebaseClient:  firebaseClient,
-		storageClient:   storageClient,
-		bucket:          configs.CloudStorageBucket,
-		googleMapApiKey: configs.GoogleMapApiKey,
-		certFile:        configs.CertFile,
-		keyFile:         configs.KeyFile,
+		port:             configs.Port,
+		db:               db,
+		identityProvider: identityProvider,
+		blobStore:        blobStore,
+		mailSender:       mailSender,
+		baseUrl:          configs.BaseUrl,
+		googleMapApiKey:  configs.GoogleMapApiKey,
+		certFile:         configs.CertFile,
+		keyFile:          configs.KeyFile,
 	}
 }
 
@@ -59,30 +66,42 @@ func (s *Server) Run() error {
 	router := mux.NewRouter()
 	subRouter := router.PathPrefix("/api/v1").Subrouter()
 
-	ctx := context.Background()
+	sessionStore := session.NewSessionStore(s.db, logger)
+	sessionService := session.NewSessionService(sessionStore, logger)
+
+	userStore := user.NewUserStore(s.db, logger)
+	userService := user.NewUserService(userStore, s.identityProvider, s.mailSender, s.baseUrl, logger)
+
 	// middlewares
-	firebaseAdminClient, err := s.firebaseApp.Auth(ctx)
-	if err != nil {
-		logger.Error("Cannot initialize Firebase admin client", slog.Any("err", err))
-		os.Exit(1)
-	}
 	router.Use(
 		middleware.LoggerMiddleWare(logger),
 		middleware.ContentTypeHeaderMiddleWare,
-		middleware.AuthenticateMiddleWare(s.firebaseClient, logger),
+		middleware.CsrfMiddleware(logger),
+		middleware.AuthenticateMiddleWare(sessionService, s.identityProvider, userService, logger),
 	)
 
 	// REST API handler
-	userStore := user.NewUserStore(s.db, logger)
-	userService := user.NewUserService(userStore, firebaseAdminClient, logger)
 	userHandler := rest_api.NewUserHandler(userService)
 	userHandler.RegisterRoutes(subRouter)
 
+	sportStore := sport.NewSportStore(s.db, logger)
+	sportService := sport.NewSportService(sportStore, logger)
+
+	googleMapsClient := client.NewGoogleMapsClient(s.googleMapApiKey)
+	cityStore := city.NewCityStore(s.db, logger)
+	cityService := city.NewCityService(cityStore, googleMapsClient, logger)
+
 	poiStore := poi.NewPoiStore(s.db, logger)
-	poiService := poi.NewPoiService(poiStore, logger)
-	poiHandler := rest_api.NewPoiHandler(poiService, s.firebaseClient, s.storageClient, s.bucket)
+	poiService := poi.NewPoiService(poiStore, sportService, cityService, logger)
+	poiHandler := rest_api.NewPoiHandler(poiService, s.identityProvider, userService)
 	poiHandler.RegisterRoutes(subRouter)
 
+	sportHandler := rest_api.NewSportHandler(sportService)
+	sportHandler.RegisterRoutes(subRouter)
+
+	cityHandler := rest_api.NewCityHandler(cityService)
+	cityHandler.RegisterRoutes(subRouter)
+
 	// HTML handler
 	homeHandler := web.NewHomeHandler(logger)
 	homeHandler.RegisterRoutes(router)
@@ -90,13 +109,30 @@ func (s *Server) Run() error {
 	registerHandler := web.NewRegisterHandler(userService, logger)
 	registerHandler.RegisterRoutes(router)
 
-	loginHandler := web.NewLoginHandler(userService, s.firebaseClient, logger)
+	loginHandler := web.NewLoginHandler(userService, sessionService, s.identityProvider, logger)
 	loginHandler.RegisterRoutes(router)
 
-	whereToPlay := web.NewWhereToPlayHandler(logger, poiService, s.storageClient, s.bucket, s.googleMapApiKey)
+	sessionHandler := web.NewSessionHandler(sessionService, s.identityProvider, logger)
+	sessionHandler.RegisterRoutes(router)
+
+	accountHandler := web.NewAccountHandler(userService, sessionService, s.identityProvider, logger)
+	accountHandler.RegisterRoutes(router)
+
+	moderationHandler := web.NewModerationHandler(poiService, userService, s.blobStore, logger)
+	moderationHandler.RegisterRoutes(router)
+
+	profileHandler := web.NewProfileHandler(userService, poiService, logger)
+	profileHandler.RegisterRoutes(router)
+
+	whereToPlay := web.NewWhereToPlayHandler(logger, poiService, sportService, cityService, s.blobStore, s.googleMapApiKey)
 	whereToPlay.RegisterRoutes(router)
 
+	// memstats and the command line are for admins only
+	router.Handle("/debug/vars", middleware.RequireRole(expvar.Handler(), user.RoleAdmin)).Methods(http.MethodGet)
 	router.PathPrefix("/static/").Handler(http.StripPrefix("/static/", http.FileServer(http.FS(static.Assets))))
+	if localBlobStore, ok := s.blobStore.(*blob.LocalBlobStore); ok {
+		router.PathPrefix(LocalBlobUrlPrefix + "/").Handler(localBlobStore.Handler())
+	}
 
 	router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
 		path, _ := route.GetPathTemplate()
@@ -1,7 +1,9 @@
 package configs
 
 import (
+	"fmt"
 	"os"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
@@ -16,13 +18,39 @@ type Config struct {
 	DBMigrationDir     string
 	GoogleMapApiKey    string
 	GCPApiKey          string
+	FirebaseWebApiKey  string
 	CloudStorageBucket string
+	BlobStore          string
+	LocalBlobDir       string
+	BlobSigningKey     string
+	IdentityProvider   string
+	LocalAuthKeyFile   string
+	BaseUrl            string
+	MailSender         string
+	MailFrom           string
+	MailDir            string
+	SmtpHost           string
+	SmtpPort           string
+	SmtpUsername       string
+	SmtpPassword       string
+	AdminUserIds       []string
 	CertFile           string
 	KeyFile            string
 }
 
 var Envs = initConfig()
 
+// Postgres connection string for the configured database
+func (c Config) DSN() string {
+	return fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable&TimeZone=UTC",
+		c.DBUser,
+		c.DBPassword,
+		c.DBHost,
+		c.DBPort,
+		c.DBName,
+	)
+}
+
 func initConfig() Config {
 	godotenv.Load()
 
@@ -36,7 +64,22 @@ func initConfig() Config {
 		DBMigrationDir:     getEnv("DB_MIGRATION_DIR", "/db/migrations"),
 		GoogleMapApiKey:    getEnv("GOOGLE_MAP_API_KEY", "apiKey"),
 		GCPApiKey:          getEnv("GCP_SERVICE_ACCOUNT_API_KEY", "apiKey"),
+		FirebaseWebApiKey:  getEnv("FIREBASE_WEB_API_KEY", ""),
 		CloudStorageBucket: getEnv("CLOUD_STORAGE_BUCKET", "sportspazz"),
+		BlobStore:          getEnv("BLOB_STORE", "gcs"),
+		LocalBlobDir:       getEnv("LOCAL_BLOB_DIR", "tmp/blobs"),
+		BlobSigningKey:     getEnv("BLOB_SIGNING_KEY", ""),
+		IdentityProvider:   getEnv("IDENTITY_PROVIDER", "firebase"),
+		LocalAuthKeyFile:   getEnv("LOCAL_AUTH_KEY_FILE", ""),
+		BaseUrl:            getEnv("BASE_URL", "http://localhost:4001"),
+		MailSender:         getEnv("MAIL_SENDER", "log"),
+		MailFrom:           getEnv("MAIL_FROM", "Sportspazz <no-reply@sportspazz.com>"),
+		MailDir:            getEnv("MAIL_DIR", "tmp/mail"),
+		SmtpHost:           getEnv("SMTP_HOST", "localhost"),
+		SmtpPort:           getEnv("SMTP_PORT", "587"),
+		SmtpUsername:       getEnv("SMTP_USERNAME", ""),
+		SmtpPassword:       getEnv("SMTP_PASSWORD", ""),
+		AdminUserIds:       getEnvList("ADMIN_USER_IDS"),
 		CertFile:           getEnv("CERT_FILE", ""),
 		KeyFile:            getEnv("KEY_FILE", ""),
 	}
@@ -49,3 +92,14 @@ func getEnv(key, _default string) string {
 
 	return _default
 }
+
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(getEnv(key, ""), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+
+	return values
+}
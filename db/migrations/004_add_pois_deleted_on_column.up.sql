@@ -0,0 +1,5 @@
+ALTER TABLE pois ADD COLUMN deleted_on TIMESTAMP(3);
+
+ALTER TABLE pois DROP CONSTRAINT unique_google_place_id;
+
+CREATE UNIQUE INDEX unique_google_place_id ON pois (google_place_id) WHERE deleted_on IS NULL;
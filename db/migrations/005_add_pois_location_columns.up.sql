@@ -0,0 +1,4 @@
+ALTER TABLE pois ADD COLUMN latitude DOUBLE PRECISION;
+ALTER TABLE pois ADD COLUMN longitude DOUBLE PRECISION;
+
+CREATE INDEX idx_pois_location ON pois (latitude, longitude);
@@ -0,0 +1,7 @@
+ALTER TABLE pois ADD COLUMN search_vector TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', coalesce(name, '')), 'A') ||
+    setweight(to_tsvector('english', coalesce(description, '')), 'B') ||
+    setweight(to_tsvector('english', coalesce(address, '')), 'C')
+) STORED;
+
+CREATE INDEX idx_pois_search_vector ON pois USING GIN (search_vector);
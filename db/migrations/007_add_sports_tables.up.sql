@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS sports (
+    internal_id BIGSERIAL PRIMARY KEY,
+    id VARCHAR(36) NOT NULL,
+    created_on TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    name VARCHAR(50) NOT NULL,
+    UNIQUE(id)
+);
+
+CREATE UNIQUE INDEX unique_sport_name ON sports (LOWER(name));
+
+ALTER TABLE pois ADD CONSTRAINT unique_poi_id UNIQUE (id);
+
+CREATE TABLE IF NOT EXISTS poi_sports (
+    poi_id VARCHAR(36) NOT NULL REFERENCES pois (id),
+    sport_id VARCHAR(36) NOT NULL REFERENCES sports (id),
+    PRIMARY KEY (poi_id, sport_id)
+);
+
+CREATE INDEX idx_poi_sports_sport_id ON poi_sports (sport_id);
+
+INSERT INTO sports (id, name)
+SELECT DISTINCT ON (LOWER(sport_type)) gen_random_uuid()::TEXT, sport_type
+FROM pois
+ORDER BY LOWER(sport_type), internal_id;
+
+INSERT INTO poi_sports (poi_id, sport_id)
+SELECT pois.id, sports.id
+FROM pois
+JOIN sports ON LOWER(sports.name) = LOWER(pois.sport_type);
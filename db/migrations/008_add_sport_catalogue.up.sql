@@ -0,0 +1,75 @@
+CREATE TABLE IF NOT EXISTS sport_categories (
+    internal_id BIGSERIAL PRIMARY KEY,
+    id VARCHAR(36) NOT NULL,
+    slug VARCHAR(50) NOT NULL,
+    name VARCHAR(50) NOT NULL,
+    UNIQUE(id),
+    UNIQUE(slug)
+);
+
+ALTER TABLE sports ADD COLUMN slug VARCHAR(50);
+ALTER TABLE sports ADD COLUMN icon VARCHAR(16);
+ALTER TABLE sports ADD COLUMN category_id VARCHAR(36) REFERENCES sport_categories (id);
+
+UPDATE sports SET slug = TRIM(BOTH '-' FROM LOWER(REGEXP_REPLACE(name, '[^a-zA-Z0-9]+', '-', 'g')));
+
+ALTER TABLE sports ALTER COLUMN slug SET NOT NULL;
+
+CREATE UNIQUE INDEX unique_sport_slug ON sports (slug);
+
+CREATE TABLE IF NOT EXISTS sport_aliases (
+    alias VARCHAR(50) NOT NULL,
+    sport_id VARCHAR(36) NOT NULL REFERENCES sports (id)
+);
+
+CREATE UNIQUE INDEX unique_sport_alias ON sport_aliases (LOWER(alias));
+
+INSERT INTO sport_categories (id, slug, name) VALUES
+    (gen_random_uuid()::TEXT, 'team', 'Team sports'),
+    (gen_random_uuid()::TEXT, 'racket', 'Racket sports'),
+    (gen_random_uuid()::TEXT, 'individual', 'Individual sports');
+
+INSERT INTO sports (id, slug, name, icon, category_id)
+SELECT gen_random_uuid()::TEXT, catalogue.slug, catalogue.name, catalogue.icon, sport_categories.id
+FROM (VALUES
+    ('soccer', 'Soccer', '⚽', 'team'),
+    ('football', 'Football', '🏈', 'team'),
+    ('basketball', 'Basketball', '🏀', 'team'),
+    ('baseball', 'Baseball', '⚾', 'team'),
+    ('volleyball', 'Volleyball', '🏐', 'team'),
+    ('hockey', 'Hockey', '🏒', 'team'),
+    ('cricket', 'Cricket', '🏏', 'team'),
+    ('tennis', 'Tennis', '🎾', 'racket'),
+    ('badminton', 'Badminton', '🏸', 'racket'),
+    ('pickleball', 'Pickleball', '🏓', 'racket'),
+    ('table-tennis', 'Table Tennis', '🏓', 'racket'),
+    ('golf', 'Golf', '⛳', 'individual'),
+    ('running', 'Running', '🏃', 'individual'),
+    ('cycling', 'Cycling', '🚴', 'individual'),
+    ('swimming', 'Swimming', '🏊', 'individual')
+) AS catalogue (slug, name, icon, category)
+JOIN sport_categories ON sport_categories.slug = catalogue.category
+ON CONFLICT (LOWER(name)) DO UPDATE
+SET slug = EXCLUDED.slug, icon = EXCLUDED.icon, category_id = EXCLUDED.category_id;
+
+INSERT INTO sport_aliases (alias, sport_id)
+SELECT aliases.alias, sports.id
+FROM (VALUES
+    ('futbol', 'soccer'),
+    ('association football', 'soccer'),
+    ('american football', 'football'),
+    ('canadian football', 'football'),
+    ('gridiron', 'football'),
+    ('basket', 'basketball'),
+    ('hoops', 'basketball'),
+    ('softball', 'baseball'),
+    ('beach volleyball', 'volleyball'),
+    ('ice hockey', 'hockey'),
+    ('field hockey', 'hockey'),
+    ('ping pong', 'table-tennis'),
+    ('jogging', 'running'),
+    ('biking', 'cycling'),
+    ('swim', 'swimming')
+) AS aliases (alias, slug)
+JOIN sports ON sports.slug = aliases.slug
+ON CONFLICT DO NOTHING;
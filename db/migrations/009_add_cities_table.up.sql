@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS cities (
+    internal_id BIGSERIAL PRIMARY KEY,
+    id VARCHAR(36) NOT NULL,
+    created_on TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    place_id VARCHAR(255) NOT NULL,
+    slug VARCHAR(150) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    region VARCHAR(100),
+    country VARCHAR(100),
+    latitude DOUBLE PRECISION,
+    longitude DOUBLE PRECISION,
+    time_zone VARCHAR(64),
+    UNIQUE(id),
+    UNIQUE(place_id),
+    UNIQUE(slug)
+);
+
+CREATE INDEX idx_cities_name ON cities (LOWER(name));
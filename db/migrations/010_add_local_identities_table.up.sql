@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS local_identities (
+    internal_id BIGSERIAL PRIMARY KEY,
+    id VARCHAR(36) NOT NULL,
+    created_on TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_on TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    email VARCHAR(320) NOT NULL,
+    password_hash VARCHAR(60) NOT NULL,
+    email_verified BOOLEAN NOT NULL DEFAULT FALSE,
+    tokens_valid_after TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(id)
+);
+
+CREATE UNIQUE INDEX unique_local_identities_email ON local_identities (LOWER(email));
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS sessions (
+    internal_id BIGSERIAL PRIMARY KEY,
+    id VARCHAR(36) NOT NULL,
+    created_on TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_seen_on TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_on TIMESTAMP(3) NOT NULL,
+    revoked_on TIMESTAMP(3),
+    user_id VARCHAR(36) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL,
+    user_agent VARCHAR(512),
+    ip_address VARCHAR(45),
+    UNIQUE(id),
+    UNIQUE(token_hash)
+);
+
+CREATE INDEX idx_sessions_user_id ON sessions (user_id) WHERE revoked_on IS NULL;
@@ -0,0 +1,16 @@
+ALTER TABLE users ADD COLUMN email_verified BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS user_tokens (
+    internal_id BIGSERIAL PRIMARY KEY,
+    id VARCHAR(36) NOT NULL,
+    created_on TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_on TIMESTAMP(3) NOT NULL,
+    used_on TIMESTAMP(3),
+    user_id VARCHAR(36) NOT NULL,
+    purpose VARCHAR(32) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL,
+    UNIQUE(id),
+    UNIQUE(token_hash)
+);
+
+CREATE INDEX idx_user_tokens_user_id ON user_tokens (user_id);
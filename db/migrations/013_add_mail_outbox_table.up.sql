@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS mail_outbox (
+    internal_id BIGSERIAL PRIMARY KEY,
+    id VARCHAR(36) NOT NULL,
+    created_on TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_on TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    recipient VARCHAR(320) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    text_body TEXT NOT NULL,
+    html_body TEXT NOT NULL DEFAULT '',
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_on TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_error TEXT,
+    sent_on TIMESTAMP(3),
+    UNIQUE(id)
+);
+
+CREATE INDEX idx_mail_outbox_pending ON mail_outbox (next_attempt_on) WHERE status = 'pending';
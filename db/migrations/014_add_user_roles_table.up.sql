@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS user_roles (
+    user_id VARCHAR(36) NOT NULL REFERENCES users (id),
+    role VARCHAR(32) NOT NULL,
+    created_on TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_by VARCHAR(36),
+    PRIMARY KEY (user_id, role)
+);
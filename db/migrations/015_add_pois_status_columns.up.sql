@@ -0,0 +1,6 @@
+ALTER TABLE pois ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'approved';
+ALTER TABLE pois ADD COLUMN moderated_by VARCHAR(36);
+ALTER TABLE pois ADD COLUMN moderated_on TIMESTAMP(3);
+ALTER TABLE pois ADD COLUMN rejection_reason TEXT;
+
+CREATE INDEX idx_pois_pending ON pois (created_on) WHERE status = 'pending' AND deleted_on IS NULL;
@@ -0,0 +1,34 @@
+CREATE TABLE IF NOT EXISTS poi_revisions (
+    internal_id BIGSERIAL PRIMARY KEY,
+    id VARCHAR(36) NOT NULL,
+    poi_id VARCHAR(36) NOT NULL REFERENCES pois (id),
+    created_on TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_by VARCHAR(36) NOT NULL,
+    action VARCHAR(16) NOT NULL,
+    snapshot JSONB NOT NULL,
+    UNIQUE(id)
+);
+
+CREATE INDEX idx_poi_revisions_poi_id ON poi_revisions (poi_id, internal_id);
+
+-- existing places start their history with their current content
+INSERT INTO poi_revisions (id, poi_id, created_on, created_by, action, snapshot)
+SELECT gen_random_uuid()::TEXT, pois.id, pois.updated_on, pois.updated_by, 'create', json_build_object(
+    'name', pois.name,
+    'address', pois.address,
+    'website', pois.website,
+    'city_id', pois.city_id,
+    'latitude', pois.latitude,
+    'longitude', pois.longitude,
+    'sport_type', pois.sport_type,
+    'sports', COALESCE((SELECT json_agg(sports.name ORDER BY sports.name)
+        FROM poi_sports JOIN sports ON sports.id = poi_sports.sport_id
+        WHERE poi_sports.poi_id = pois.id), '[]'::json),
+    'thumbnail_url', pois.thumbnail_url,
+    'description', pois.description,
+    'note', pois.note,
+    'status', pois.status,
+    'rejection_reason', pois.rejection_reason,
+    'deleted', pois.deleted_on IS NOT NULL
+)
+FROM pois;
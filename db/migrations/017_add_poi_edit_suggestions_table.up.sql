@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS poi_edit_suggestions (
+    internal_id BIGSERIAL PRIMARY KEY,
+    id VARCHAR(36) NOT NULL,
+    poi_id VARCHAR(36) NOT NULL REFERENCES pois (id),
+    created_on TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_by VARCHAR(36) NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    changes JSONB NOT NULL,
+    comment TEXT,
+    reviewed_by VARCHAR(36),
+    reviewed_on TIMESTAMP(3),
+    review_note TEXT,
+    UNIQUE(id)
+);
+
+CREATE INDEX idx_poi_edit_suggestions_pending ON poi_edit_suggestions (created_on) WHERE status = 'pending';
+CREATE INDEX idx_poi_edit_suggestions_created_by ON poi_edit_suggestions (created_by, status);
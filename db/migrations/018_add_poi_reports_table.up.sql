@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS poi_reports (
+    internal_id BIGSERIAL PRIMARY KEY,
+    id VARCHAR(36) NOT NULL,
+    poi_id VARCHAR(36) NOT NULL REFERENCES pois (id),
+    created_on TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_by VARCHAR(36) NOT NULL,
+    reason VARCHAR(32) NOT NULL,
+    duplicate_of VARCHAR(36) REFERENCES pois (id),
+    comment TEXT,
+    resolved_on TIMESTAMP(3),
+    resolved_by VARCHAR(36),
+    resolution VARCHAR(16),
+    UNIQUE(id)
+);
+
+-- a user has at most one open report per place, so reports are independent
+CREATE UNIQUE INDEX unique_open_poi_report ON poi_reports (poi_id, created_by) WHERE resolved_on IS NULL;
+CREATE INDEX idx_poi_reports_created_by ON poi_reports (created_by, created_on);
+
+ALTER TABLE pois ADD COLUMN hidden_on TIMESTAMP(3);
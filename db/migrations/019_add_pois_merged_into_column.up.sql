@@ -0,0 +1 @@
+ALTER TABLE pois ADD COLUMN merged_into VARCHAR(36);
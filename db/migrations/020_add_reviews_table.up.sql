@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS reviews (
+    internal_id BIGSERIAL PRIMARY KEY,
+    id VARCHAR(36) NOT NULL,
+    poi_id VARCHAR(36) NOT NULL REFERENCES pois (id),
+    created_on TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_on TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_by VARCHAR(36) NOT NULL,
+    stars SMALLINT NOT NULL CHECK (stars BETWEEN 1 AND 5),
+    text TEXT,
+    visited_on DATE,
+    UNIQUE(id),
+    -- one review per user and place, edited rather than added again
+    UNIQUE(poi_id, created_by)
+);
+
+-- kept in sync with the reviews so search results can be sorted by rating
+ALTER TABLE pois ADD COLUMN rating NUMERIC(3, 2);
+ALTER TABLE pois ADD COLUMN rating_count INTEGER NOT NULL DEFAULT 0;
+CREATE INDEX idx_pois_rating ON pois (rating DESC NULLS LAST, rating_count DESC) WHERE deleted_on IS NULL;
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS poi_photos (
+    internal_id BIGSERIAL PRIMARY KEY,
+    id VARCHAR(36) NOT NULL,
+    poi_id VARCHAR(36) NOT NULL REFERENCES pois (id),
+    created_on TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_by VARCHAR(36) NOT NULL,
+    object_name VARCHAR(512) NOT NULL,
+    url VARCHAR(1024) NOT NULL,
+    caption VARCHAR(200),
+    position INTEGER NOT NULL DEFAULT 0,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    moderated_by VARCHAR(36),
+    moderated_on TIMESTAMP(3),
+    UNIQUE(id)
+);
+
+CREATE INDEX idx_poi_photos_poi_id ON poi_photos (poi_id, position);
+CREATE INDEX idx_poi_photos_pending ON poi_photos (created_on) WHERE status = 'pending';
@@ -0,0 +1,6 @@
+-- resized JPEG and WebP encodings of the image, listed in srcset attributes
+ALTER TABLE pois ADD COLUMN thumbnail_renditions JSONB;
+ALTER TABLE poi_photos ADD COLUMN renditions JSONB;
+
+-- photos are stored as renditions only, the original upload is not kept
+ALTER TABLE poi_photos ALTER COLUMN object_name DROP NOT NULL;
@@ -0,0 +1,23 @@
+-- credits required by the source of the thumbnail, html from Google Places photos
+ALTER TABLE pois ADD COLUMN thumbnail_attributions JSONB;
+
+-- thumbnails fetched in the background from a url or a Google Places photo reference
+CREATE TABLE IF NOT EXISTS thumbnail_imports (
+    internal_id BIGSERIAL PRIMARY KEY,
+    id VARCHAR(36) NOT NULL,
+    poi_id VARCHAR(36) NOT NULL REFERENCES pois (id),
+    created_on TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_on TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_by VARCHAR(36) NOT NULL,
+    source_url VARCHAR(2048),
+    photo_reference VARCHAR(1024),
+    attributions JSONB,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_on TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_error TEXT,
+    imported_on TIMESTAMP(3),
+    UNIQUE(id)
+);
+
+CREATE INDEX idx_thumbnail_imports_pending ON thumbnail_imports (next_attempt_on) WHERE status = 'pending';
@@ -0,0 +1,2 @@
+-- thumbnail an import replaces, the import only applies while the place still has it
+ALTER TABLE thumbnail_imports ADD COLUMN replaces_thumbnail_url VARCHAR(500);
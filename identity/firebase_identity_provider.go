@@ -0,0 +1,125 @@
+package identity
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"firebase.google.com/go/v4/auth"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/sportspazz/api/client"
+)
+
+// Identity provider backed by Firebase Authentication
+type FirebaseIdentityProvider struct {
+	firebaseClient     *client.FirebaseClient
+	firebaseAuthClient *auth.Client
+}
+
+func NewFirebaseIdentityProvider(firebaseClient *client.FirebaseClient, firebaseAuthClient *auth.Client) *FirebaseIdentityProvider {
+	return &FirebaseIdentityProvider{
+		firebaseClient:     firebaseClient,
+		firebaseAuthClient: firebaseAuthClient,
+	}
+}
+
+func (p *FirebaseIdentityProvider) CreateUser(ctx context.Context, email, password string) (string, error) {
+	params := (&auth.UserToCreate{}).
+		Email(email).
+		EmailVerified(false).
+		Password(password).
+		Disabled(false)
+	newUser, err := p.firebaseAuthClient.CreateUser(ctx, params)
+	if auth.IsEmailAlreadyExists(err) {
+		return "", ErrEmailExists
+	}
+	if err != nil {
+		return "", fmt.Errorf("error creating firebase user: %v", err)
+	}
+
+	return newUser.UID, nil
+}
+
+func (p *FirebaseIdentityProvider) UpdateUser(ctx context.Context, userId string, update UserUpdate) error {
+	params := &auth.UserToUpdate{}
+	if update.Password != nil {
+		params = params.Password(*update.Password)
+	}
+	if update.EmailVerified != nil {
+		params = params.EmailVerified(*update.EmailVerified)
+	}
+
+	if _, err := p.firebaseAuthClient.UpdateUser(ctx, userId, params); err != nil {
+		return fmt.Errorf("error updating firebase user: %v", err)
+	}
+	return nil
+}
+
+func (p *FirebaseIdentityProvider) SignIn(ctx context.Context, email, password string) (*Tokens, error) {
+	resp, err := p.firebaseClient.SignInWithEmailAndPassword(client.NewSignInWithPasswordRequest(email, password))
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	expiresIn, _ := strconv.Atoi(resp.ExpiresIn)
+	return &Tokens{
+		UserId:       resp.LocalId,
+		IdToken:      resp.IdToken,
+		RefreshToken: resp.RefreshToken,
+		ExpiresIn:    time.Duration(expiresIn) * time.Second,
+	}, nil
+}
+
+func (p *FirebaseIdentityProvider) VerifyToken(ctx context.Context, idToken string) (*Claims, error) {
+	token, err := p.firebaseClient.VerifyIDToken(idToken)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	return claimsFromToken(token)
+}
+
+func (p *FirebaseIdentityProvider) Refresh(ctx context.Context, refreshToken string) (*Tokens, error) {
+	resp, err := p.firebaseClient.RefreshIdToken(refreshToken)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	expiresIn, _ := strconv.Atoi(resp.ExpiresIn)
+	return &Tokens{
+		UserId:       resp.UserId,
+		IdToken:      resp.IdToken,
+		RefreshToken: resp.RefreshToken,
+		ExpiresIn:    time.Duration(expiresIn) * time.Second,
+	}, nil
+}
+
+func (p *FirebaseIdentityProvider) Revoke(ctx context.Context, userId string) error {
+	return p.firebaseAuthClient.RevokeRefreshTokens(ctx, userId)
+}
+
+// Reads the claims shared by Firebase and local ID tokens
+func claimsFromToken(token *jwt.Token) (*Claims, error) {
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	userId, _ := claims["user_id"].(string)
+	email, _ := claims["email"].(string)
+	emailVerified, _ := claims["email_verified"].(bool)
+	issuedAt, _ := claims["iat"].(float64)
+	expiresAt, _ := claims["exp"].(float64)
+	if userId == "" {
+		return nil, ErrInvalidToken
+	}
+
+	return &Claims{
+		UserId:        userId,
+		Email:         email,
+		EmailVerified: emailVerified,
+		IssuedAt:      time.Unix(int64(issuedAt), 0),
+		ExpiresAt:     time.Unix(int64(expiresAt), 0),
+	}, nil
+}
@@ -0,0 +1,49 @@
+package identity
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrEmailExists        = errors.New("email is already registered")
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	ErrInvalidToken       = errors.New("invalid token")
+)
+
+// Tokens issued to a signed in user
+type Tokens struct {
+	UserId       string
+	IdToken      string
+	RefreshToken string
+	ExpiresIn    time.Duration
+}
+
+// Verified content of an ID token
+type Claims struct {
+	UserId        string
+	Email         string
+	EmailVerified bool
+	IssuedAt      time.Time
+	ExpiresAt     time.Time
+}
+
+// Changes to a user account, nil fields are left untouched
+type UserUpdate struct {
+	Password      *string
+	EmailVerified *bool
+}
+
+// Creates and authenticates users, backed by Firebase or by the local database
+type IdentityProvider interface {
+	// Returns the id of the new user
+	CreateUser(ctx context.Context, email, password string) (string, error)
+	UpdateUser(ctx context.Context, userId string, update UserUpdate) error
+	SignIn(ctx context.Context, email, password string) (*Tokens, error)
+	VerifyToken(ctx context.Context, idToken string) (*Claims, error)
+	// Exchanges a refresh token for a new ID token
+	Refresh(ctx context.Context, refreshToken string) (*Tokens, error)
+	// Invalidates all refresh tokens of the user
+	Revoke(ctx context.Context, userId string) error
+}
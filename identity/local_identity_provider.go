@@ -0,0 +1,187 @@
+package identity
+
+import (
+	"context"
+	"crypto/rsa"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	localIssuer          = "sportspazz-local"
+	localKeyId           = "local"
+	localIdTokenTTL      = time.Hour
+	localRefreshTokenTTL = 30 * 24 * time.Hour
+	idTokenUse           = "id"
+	refreshTokenUse      = "refresh"
+)
+
+// Self-contained identity provider keeping bcrypt password hashes in the database
+// and signing its own RS256 tokens, so the app runs without Firebase.
+type LocalIdentityProvider struct {
+	store      *LocalIdentityStore
+	privateKey *rsa.PrivateKey
+	logger     *slog.Logger
+}
+
+func NewLocalIdentityProvider(store *LocalIdentityStore, privateKey *rsa.PrivateKey, logger *slog.Logger) *LocalIdentityProvider {
+	return &LocalIdentityProvider{
+		store:      store,
+		privateKey: privateKey,
+		logger:     logger,
+	}
+}
+
+func (p *LocalIdentityProvider) CreateUser(ctx context.Context, email, password string) (string, error) {
+	if p.store.GetIdentityByEmail(email) != nil {
+		return "", ErrEmailExists
+	}
+
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("error hashing password: %v", err)
+	}
+
+	identity, err := p.store.CreateIdentity(email, string(passwordHash))
+	if err != nil {
+		return "", err
+	}
+
+	return identity.ID, nil
+}
+
+func (p *LocalIdentityProvider) UpdateUser(ctx context.Context, userId string, update UserUpdate) error {
+	updates := map[string]interface{}{}
+	if update.Password != nil {
+		passwordHash, err := bcrypt.GenerateFromPassword([]byte(*update.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return fmt.Errorf("error hashing password: %v", err)
+		}
+		updates["password_hash"] = string(passwordHash)
+	}
+	if update.EmailVerified != nil {
+		updates["email_verified"] = *update.EmailVerified
+	}
+
+	return p.store.UpdateIdentity(userId, updates)
+}
+
+func (p *LocalIdentityProvider) SignIn(ctx context.Context, email, password string) (*Tokens, error) {
+	identity := p.store.GetIdentityByEmail(email)
+	if identity == nil {
+		return nil, ErrInvalidCredentials
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(identity.PasswordHash), []byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	refreshToken, err := p.sign(identity, refreshTokenUse, localRefreshTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.issueTokens(identity, refreshToken)
+}
+
+func (p *LocalIdentityProvider) VerifyToken(ctx context.Context, idToken string) (*Claims, error) {
+	token, err := p.parse(idToken, idTokenUse)
+	if err != nil {
+		return nil, err
+	}
+
+	return claimsFromToken(token)
+}
+
+func (p *LocalIdentityProvider) Refresh(ctx context.Context, refreshToken string) (*Tokens, error) {
+	token, err := p.parse(refreshToken, refreshTokenUse)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := claimsFromToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	identity := p.store.GetIdentityById(claims.UserId)
+	if identity == nil {
+		return nil, ErrInvalidToken
+	}
+	if claims.IssuedAt.Before(identity.TokensValidAfter) {
+		return nil, fmt.Errorf("%w: refresh token has been revoked", ErrInvalidToken)
+	}
+
+	return p.issueTokens(identity, refreshToken)
+}
+
+// Token timestamps have a precision of seconds, so tokens issued during the
+// current second are revoked too
+func (p *LocalIdentityProvider) Revoke(ctx context.Context, userId string) error {
+	return p.store.RevokeTokens(userId, time.Now().UTC().Truncate(time.Second).Add(time.Second))
+}
+
+func (p *LocalIdentityProvider) issueTokens(identity *LocalIdentity, refreshToken string) (*Tokens, error) {
+	idToken, err := p.sign(identity, idTokenUse, localIdTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Tokens{
+		UserId:       identity.ID,
+		IdToken:      idToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    localIdTokenTTL,
+	}, nil
+}
+
+// Signs a token carrying the same claims as a Firebase ID token
+func (p *LocalIdentityProvider) sign(identity *LocalIdentity, tokenUse string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":            localIssuer,
+		"aud":            localIssuer,
+		"sub":            identity.ID,
+		"iat":            now.Unix(),
+		"exp":            now.Add(ttl).Unix(),
+		"user_id":        identity.ID,
+		"email":          identity.Email,
+		"email_verified": identity.EmailVerified,
+		"token_use":      tokenUse,
+	})
+	token.Header["kid"] = localKeyId
+
+	signed, err := token.SignedString(p.privateKey)
+	if err != nil {
+		return "", fmt.Errorf("error signing token: %v", err)
+	}
+	return signed, nil
+}
+
+func (p *LocalIdentityProvider) parse(tokenString, tokenUse string) (*jwt.Token, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return &p.privateKey.PublicKey, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, ErrInvalidToken
+	}
+	if !claims.VerifyIssuer(localIssuer, true) || !claims.VerifyAudience(localIssuer, true) {
+		return nil, fmt.Errorf("%w: invalid issuer or audience", ErrInvalidToken)
+	}
+	if use, _ := claims["token_use"].(string); use != tokenUse {
+		return nil, fmt.Errorf("%w: unexpected token use", ErrInvalidToken)
+	}
+
+	return token, nil
+}
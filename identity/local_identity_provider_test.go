@@ -0,0 +1,177 @@
+package identity
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Local provider backed by a SQLite database created from the Postgres migration
+func newTestProvider(t *testing.T) *LocalIdentityProvider {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "identity.db")), &gorm.Config{
+		Logger: logger.Discard,
+	})
+	if err != nil {
+		t.Fatalf("cannot open database: %v", err)
+	}
+	migration, err := os.ReadFile("../db/migrations/010_add_local_identities_table.up.sql")
+	if err != nil {
+		t.Fatalf("cannot read migration: %v", err)
+	}
+	// the SQLite driver only parses the times of DATETIME columns
+	schema := strings.ReplaceAll(string(migration), "TIMESTAMP(3)", "DATETIME")
+	if err := db.Exec(schema).Error; err != nil {
+		t.Fatalf("cannot apply migration: %v", err)
+	}
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("cannot generate key: %v", err)
+	}
+	discard := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return NewLocalIdentityProvider(NewLocalIdentityStore(db, discard), privateKey, discard)
+}
+
+// Waits for the next second, token timestamps have a precision of seconds
+func waitNextSecond() {
+	time.Sleep(time.Until(time.Now().Truncate(time.Second).Add(time.Second)))
+}
+
+func TestLocalIdentityProviderSignUpAndSignIn(t *testing.T) {
+	ctx := context.Background()
+	provider := newTestProvider(t)
+
+	userId, err := provider.CreateUser(ctx, "Player@example.com", "secret-password")
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if _, err := provider.CreateUser(ctx, "player@example.com", "other-password"); !errors.Is(err, ErrEmailExists) {
+		t.Errorf("CreateUser with a registered email: got %v, want ErrEmailExists", err)
+	}
+
+	if _, err := provider.SignIn(ctx, "player@example.com", "wrong-password"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("SignIn with a wrong password: got %v, want ErrInvalidCredentials", err)
+	}
+	if _, err := provider.SignIn(ctx, "nobody@example.com", "secret-password"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("SignIn with an unknown email: got %v, want ErrInvalidCredentials", err)
+	}
+
+	tokens, err := provider.SignIn(ctx, "player@example.com", "secret-password")
+	if err != nil {
+		t.Fatalf("SignIn: %v", err)
+	}
+	if tokens.UserId != userId {
+		t.Errorf("SignIn user id: got %q, want %q", tokens.UserId, userId)
+	}
+
+	claims, err := provider.VerifyToken(ctx, tokens.IdToken)
+	if err != nil {
+		t.Fatalf("VerifyToken: %v", err)
+	}
+	if claims.UserId != userId || claims.Email != "Player@example.com" || claims.EmailVerified {
+		t.Errorf("VerifyToken claims: got %+v", claims)
+	}
+
+	// refresh and ID tokens are not interchangeable
+	if _, err := provider.VerifyToken(ctx, tokens.RefreshToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("VerifyToken with a refresh token: got %v, want ErrInvalidToken", err)
+	}
+	if _, err := provider.Refresh(ctx, tokens.IdToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Refresh with an ID token: got %v, want ErrInvalidToken", err)
+	}
+
+	refreshed, err := provider.Refresh(ctx, tokens.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if _, err := provider.VerifyToken(ctx, refreshed.IdToken); err != nil {
+		t.Errorf("VerifyToken of a refreshed token: %v", err)
+	}
+}
+
+func TestLocalIdentityProviderUpdateUser(t *testing.T) {
+	ctx := context.Background()
+	provider := newTestProvider(t)
+
+	userId, err := provider.CreateUser(ctx, "player@example.com", "secret-password")
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	password, verified := "new-password", true
+	if err := provider.UpdateUser(ctx, userId, UserUpdate{Password: &password, EmailVerified: &verified}); err != nil {
+		t.Fatalf("UpdateUser: %v", err)
+	}
+
+	if _, err := provider.SignIn(ctx, "player@example.com", "secret-password"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("SignIn with the old password: got %v, want ErrInvalidCredentials", err)
+	}
+	tokens, err := provider.SignIn(ctx, "player@example.com", "new-password")
+	if err != nil {
+		t.Fatalf("SignIn with the new password: %v", err)
+	}
+	claims, err := provider.VerifyToken(ctx, tokens.IdToken)
+	if err != nil {
+		t.Fatalf("VerifyToken: %v", err)
+	}
+	if !claims.EmailVerified {
+		t.Error("VerifyToken: email is not verified")
+	}
+}
+
+func TestLocalIdentityProviderRevoke(t *testing.T) {
+	ctx := context.Background()
+	provider := newTestProvider(t)
+
+	userId, err := provider.CreateUser(ctx, "player@example.com", "secret-password")
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	waitNextSecond()
+	earlier, err := provider.SignIn(ctx, "player@example.com", "secret-password")
+	if err != nil {
+		t.Fatalf("SignIn: %v", err)
+	}
+	waitNextSecond()
+	// issued during the same second as the revocation
+	sameSecond, err := provider.SignIn(ctx, "player@example.com", "secret-password")
+	if err != nil {
+		t.Fatalf("SignIn: %v", err)
+	}
+
+	if err := provider.Revoke(ctx, userId); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+	validAfter := provider.store.GetIdentityById(userId).TokensValidAfter
+	if !validAfter.Equal(validAfter.Truncate(time.Second)) {
+		t.Errorf("tokens_valid_after %v is not a whole second", validAfter)
+	}
+
+	for name, tokens := range map[string]*Tokens{"earlier": earlier, "same second": sameSecond} {
+		if _, err := provider.Refresh(ctx, tokens.RefreshToken); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("Refresh of a %s token: got %v, want ErrInvalidToken", name, err)
+		}
+	}
+
+	waitNextSecond()
+	later, err := provider.SignIn(ctx, "player@example.com", "secret-password")
+	if err != nil {
+		t.Fatalf("SignIn after Revoke: %v", err)
+	}
+	if _, err := provider.Refresh(ctx, later.RefreshToken); err != nil {
+		t.Errorf("Refresh of a token issued after Revoke: %v", err)
+	}
+}
@@ -0,0 +1,87 @@
+package identity
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Account of the local identity provider
+type LocalIdentity struct {
+	internalId       uint `gorm:"primaryKey"`
+	ID               string
+	CreatedOn        time.Time
+	UpdatedOn        time.Time
+	Email            string
+	PasswordHash     string
+	EmailVerified    bool
+	TokensValidAfter time.Time
+}
+
+type LocalIdentityStore struct {
+	db     *gorm.DB
+	logger *slog.Logger
+}
+
+func NewLocalIdentityStore(db *gorm.DB, logger *slog.Logger) *LocalIdentityStore {
+	return &LocalIdentityStore{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (s *LocalIdentityStore) CreateIdentity(email, passwordHash string) (*LocalIdentity, error) {
+	now := time.Now().UTC()
+	identity := &LocalIdentity{
+		ID:           uuid.New().String(),
+		CreatedOn:    now,
+		UpdatedOn:    now,
+		Email:        email,
+		PasswordHash: passwordHash,
+		// token timestamps have a precision of seconds
+		TokensValidAfter: now.Truncate(time.Second),
+	}
+
+	if err := s.db.Create(identity).Error; err != nil {
+		s.logger.Error("not able to create a new local identity", slog.Any("err", err))
+		return nil, err
+	}
+
+	return identity, nil
+}
+
+func (s *LocalIdentityStore) GetIdentityById(id string) *LocalIdentity {
+	var identity LocalIdentity
+	if err := s.db.First(&identity, "id = ?", id).Error; err != nil {
+		return nil
+	}
+	return &identity
+}
+
+func (s *LocalIdentityStore) GetIdentityByEmail(email string) *LocalIdentity {
+	var identity LocalIdentity
+	if err := s.db.First(&identity, "LOWER(email) = LOWER(?)", email).Error; err != nil {
+		return nil
+	}
+	return &identity
+}
+
+func (s *LocalIdentityStore) UpdateIdentity(id string, updates map[string]interface{}) error {
+	if len(updates) == 0 {
+		return nil
+	}
+	updates["updated_on"] = time.Now().UTC()
+
+	return s.db.Model(&LocalIdentity{}).Where("id = ?", id).Updates(updates).Error
+}
+
+func (s *LocalIdentityStore) RevokeTokens(id string, validAfter time.Time) error {
+	return s.db.Model(&LocalIdentity{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"tokens_valid_after": validAfter,
+			"updated_on":         time.Now().UTC(),
+		}).Error
+}
@@ -0,0 +1,234 @@
+package imaging
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"math"
+
+	"github.com/sportspazz/blob"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	// uploads larger than this are rejected before decoding
+	MaxImageSize = 15 * 1024 * 1024 // 15 MB
+	// guards against small files decoding into huge bitmaps, a 25 megapixel
+	// image takes up to 100 MB once decoded
+	maxPixels = 25_000_000
+	// images decoded at the same time, uploads carry several images each
+	maxConcurrentDecodes = 2
+	jpegQuality          = 82
+	// width of the rendition used where srcset is not supported
+	FallbackWidth = 800
+
+	ContentTypeJpeg = "image/jpeg"
+	ContentTypeWebp = "image/webp"
+)
+
+var (
+	ErrUnsupportedImage = errors.New("not a JPEG, PNG or WebP image")
+	ErrImageTooLarge    = errors.New("image dimensions are too large")
+	ErrFileTooLarge     = errors.New("image must be less than 15 MB")
+)
+
+var decodeSlots = make(chan struct{}, maxConcurrentDecodes)
+
+// Widths the uploaded images are resized to, smallest first
+var sizes = []struct {
+	name  string
+	width int
+}{
+	{"card", 400},
+	{"details", 800},
+	{"full", 1600},
+}
+
+// One encoding of a resized image. Data is set until the rendition is stored,
+// Object and Url once it is.
+type Rendition struct {
+	Name        string `json:"name"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	ContentType string `json:"content_type"`
+	Object      string `json:"object"`
+	Url         string `json:"url"`
+	Data        []byte `json:"-"`
+}
+
+// Reads the image, failing once more than MaxImageSize bytes were read
+func Read(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, MaxImageSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxImageSize {
+		return nil, ErrFileTooLarge
+	}
+	return data, nil
+}
+
+// Decodes the image whatever its file name or declared content type claims,
+// and re-encodes it into JPEG and WebP renditions. Re-encoding the pixels drops all
+// metadata, EXIF and GPS included, after the EXIF orientation was applied.
+// Waits while maxConcurrentDecodes images are being processed.
+func Process(data []byte) ([]Rendition, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || (format != "jpeg" && format != "png" && format != "webp") {
+		return nil, ErrUnsupportedImage
+	}
+	if config.Width*config.Height > maxPixels {
+		return nil, ErrImageTooLarge
+	}
+
+	decodeSlots <- struct{}{}
+	defer func() { <-decodeSlots }()
+
+	source, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+
+	orientation := 1
+	if format == "jpeg" {
+		orientation = jpegOrientation(data)
+	}
+	width, height := source.Bounds().Dx(), source.Bounds().Dy()
+	if orientation >= 5 {
+		width, height = height, width
+	}
+
+	var renditions []Rendition
+	previousWidth := 0
+	for _, size := range sizes {
+		targetWidth := min(size.width, width)
+		if targetWidth == previousWidth {
+			break
+		}
+		previousWidth = targetWidth
+		targetHeight := max(1, int(math.Round(float64(height)*float64(targetWidth)/float64(width))))
+
+		resized := resize(source, targetWidth, targetHeight, orientation)
+		encoded, err := encode(size.name, resized)
+		if err != nil {
+			return nil, err
+		}
+		renditions = append(renditions, encoded...)
+	}
+
+	return renditions, nil
+}
+
+// Scales before applying the orientation, so only the small image is rotated
+func resize(source image.Image, width, height, orientation int) *image.NRGBA {
+	scaledWidth, scaledHeight := width, height
+	if orientation >= 5 {
+		scaledWidth, scaledHeight = height, width
+	}
+
+	scaled := image.NewNRGBA(image.Rect(0, 0, scaledWidth, scaledHeight))
+	draw.CatmullRom.Scale(scaled, scaled.Bounds(), source, source.Bounds(), draw.Src, nil)
+	return orient(scaled, orientation)
+}
+
+// Browsers without WebP support fall back to the JPEG rendition
+func encode(name string, img image.Image) ([]Rendition, error) {
+	bounds := img.Bounds()
+
+	// Neither encoding keeps an alpha channel, transparent areas become white
+	flattened := image.NewRGBA(bounds)
+	draw.Draw(flattened, bounds, image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(flattened, bounds, img, bounds.Min, draw.Over)
+
+	var jpegData bytes.Buffer
+	if err := jpeg.Encode(&jpegData, flattened, &jpeg.Options{Quality: jpegQuality}); err != nil {
+		return nil, fmt.Errorf("error encoding %s jpeg: %v", name, err)
+	}
+	var webpData bytes.Buffer
+	if err := encodeWebp(&webpData, flattened); err != nil {
+		return nil, fmt.Errorf("error encoding %s webp: %v", name, err)
+	}
+
+	return []Rendition{{
+		Name:        name,
+		Width:       bounds.Dx(),
+		Height:      bounds.Dy(),
+		ContentType: ContentTypeJpeg,
+		Data:        jpegData.Bytes(),
+	}, {
+		Name:        name,
+		Width:       bounds.Dx(),
+		Height:      bounds.Dy(),
+		ContentType: ContentTypeWebp,
+		Data:        webpData.Bytes(),
+	}}, nil
+}
+
+// Uploads the renditions under the prefix. Nothing is left behind when an upload fails.
+func Store(ctx context.Context, store blob.BlobStore, prefix string, renditions []Rendition) ([]Rendition, error) {
+	stored := make([]Rendition, 0, len(renditions))
+	for _, rendition := range renditions {
+		rendition.Object = prefix + "/" + rendition.Name + extension(rendition.ContentType)
+		if err := store.Put(ctx, rendition.Object, bytes.NewReader(rendition.Data), rendition.ContentType); err != nil {
+			Delete(ctx, store, stored)
+			return nil, err
+		}
+		rendition.Url = store.PublicURL(rendition.Object)
+		rendition.Data = nil
+		stored = append(stored, rendition)
+	}
+	return stored, nil
+}
+
+func Delete(ctx context.Context, store blob.BlobStore, renditions []Rendition) error {
+	var err error
+	for _, rendition := range renditions {
+		if deleteErr := store.Delete(ctx, rendition.Object); deleteErr != nil && !errors.Is(deleteErr, blob.ErrBlobNotFound) {
+			err = deleteErr
+		}
+	}
+	return err
+}
+
+// Url of the widest JPEG rendition not wider than maxWidth, used where srcset is not
+func JpegUrl(renditions []Rendition, maxWidth int) string {
+	url, width := "", 0
+	for _, rendition := range renditions {
+		if rendition.ContentType != ContentTypeJpeg {
+			continue
+		}
+		if url == "" || (rendition.Width <= maxWidth && rendition.Width > width) {
+			url, width = rendition.Url, rendition.Width
+		}
+	}
+	return url
+}
+
+// srcset attribute listing the renditions of the content type
+func Srcset(renditions []Rendition, contentType string) string {
+	var srcset bytes.Buffer
+	for _, rendition := range renditions {
+		if rendition.ContentType != contentType {
+			continue
+		}
+		if srcset.Len() > 0 {
+			srcset.WriteString(", ")
+		}
+		fmt.Fprintf(&srcset, "%s %dw", rendition.Url, rendition.Width)
+	}
+	return srcset.String()
+}
+
+func extension(contentType string) string {
+	if contentType == ContentTypeWebp {
+		return ".webp"
+	}
+	return ".jpg"
+}
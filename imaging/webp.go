@@ -0,0 +1,1075 @@
+package imaging
+
+import (
+	"encoding/binary"
+	"image"
+	"io"
+)
+
+// Lossy WebP encoder. The pure Go WebP packages only encode lossless, which
+// is larger than the JPEG for photos, so this writes a single VP8 key frame
+// (RFC 6386) itself. It keeps to the simple parts of the format: 16x16 luma
+// and 8x8 chroma prediction, one quantizer for the whole frame and the
+// default token probabilities.
+
+const (
+	// VP8 quantizer index, 0 is best quality and 127 worst
+	webpQuantizer = 24
+	// loop filter strength, smooths the block edges the quantizer leaves
+	webpFilterLevel = 20
+)
+
+// Prediction modes, numbered as the decoder numbers them
+const (
+	predDC = iota
+	predTM
+	predVE
+	predHE
+)
+
+// Token probability planes
+const (
+	planeY1WithY2 = iota
+	planeY2
+	planeUV
+)
+
+var (
+	bands   = [17]uint8{0, 1, 2, 3, 6, 4, 5, 6, 6, 6, 6, 6, 6, 6, 6, 7, 0}
+	zigzag  = [16]uint8{0, 1, 4, 8, 5, 2, 3, 6, 9, 12, 13, 10, 7, 11, 14, 15}
+	cat3456 = [4][]uint8{
+		{173, 148, 140},
+		{176, 155, 140, 135},
+		{180, 157, 141, 134, 130},
+		{254, 254, 243, 230, 196, 177, 153, 140, 133, 130, 129},
+	}
+)
+
+// Encodes the image as a lossy WebP. The alpha channel is dropped, callers
+// flatten transparent images first.
+func encodeWebp(w io.Writer, img *image.RGBA) error {
+	bounds := img.Bounds()
+	e := newVP8Encoder(img)
+	for mby := 0; mby < e.mbh; mby++ {
+		e.left = vp8Nonzero{}
+		for mbx := 0; mbx < e.mbw; mbx++ {
+			e.encodeMacroblock(mbx, mby)
+		}
+	}
+	modes := e.modes.finish()
+	tokens := e.tokens.finish()
+
+	frame := make([]byte, 0, 10+len(modes)+len(tokens)+1)
+	// key frame, version 0, shown, followed by the size of the first partition
+	tag := 1<<4 | uint32(len(modes))<<5
+	frame = append(frame, byte(tag), byte(tag>>8), byte(tag>>16))
+	frame = append(frame, 0x9d, 0x01, 0x2a)
+	frame = binary.LittleEndian.AppendUint16(frame, uint16(bounds.Dx()))
+	frame = binary.LittleEndian.AppendUint16(frame, uint16(bounds.Dy()))
+	frame = append(frame, modes...)
+	frame = append(frame, tokens...)
+	chunkSize := len(frame)
+	if len(frame)%2 == 1 {
+		frame = append(frame, 0)
+	}
+
+	header := make([]byte, 0, 20)
+	header = append(header, "RIFF"...)
+	header = binary.LittleEndian.AppendUint32(header, uint32(4+8+len(frame)))
+	header = append(header, "WEBPVP8 "...)
+	header = binary.LittleEndian.AppendUint32(header, uint32(chunkSize))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.Write(frame)
+	return err
+}
+
+// Whether the 4x4 blocks along each edge of a macroblock had coefficients,
+// the context the next block's tokens are coded in
+type vp8Nonzero struct {
+	y    [4]uint8
+	u, v [2]uint8
+	y2   uint8
+}
+
+type vp8Encoder struct {
+	mbw, mbh int
+	// source and reconstructed planes, padded to whole macroblocks
+	srcY, srcU, srcV []uint8
+	recY, recU, recV []uint8
+	yStride, cStride int
+
+	y1, y2, uv [2]int32 // DC and AC quantizer steps
+
+	modes, tokens vp8BoolEncoder
+	top           []vp8Nonzero
+	left          vp8Nonzero
+}
+
+func newVP8Encoder(img *image.RGBA) *vp8Encoder {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	e := &vp8Encoder{
+		mbw: (width + 15) / 16,
+		mbh: (height + 15) / 16,
+	}
+	e.yStride, e.cStride = e.mbw*16, e.mbw*8
+	e.srcY = make([]uint8, e.yStride*e.mbh*16)
+	e.srcU = make([]uint8, e.cStride*e.mbh*8)
+	e.srcV = make([]uint8, e.cStride*e.mbh*8)
+	e.recY = make([]uint8, len(e.srcY))
+	e.recU = make([]uint8, len(e.srcU))
+	e.recV = make([]uint8, len(e.srcV))
+	e.top = make([]vp8Nonzero, e.mbw)
+
+	// BT.601 limited range as libwebp converts, the padding repeats the
+	// last row and column
+	pixel := func(x, y int) (r, g, b int) {
+		x, y = min(x, width-1), min(y, height-1)
+		i := img.PixOffset(bounds.Min.X+x, bounds.Min.Y+y)
+		return int(img.Pix[i]), int(img.Pix[i+1]), int(img.Pix[i+2])
+	}
+	for y := 0; y < e.mbh*16; y++ {
+		for x := 0; x < e.mbw*16; x++ {
+			r, g, b := pixel(x, y)
+			e.srcY[y*e.yStride+x] = uint8((16839*r + 33059*g + 6420*b + 16<<16 + 1<<15) >> 16)
+		}
+	}
+	for y := 0; y < e.mbh*8; y++ {
+		for x := 0; x < e.mbw*8; x++ {
+			var r, g, b int
+			for i := 0; i < 4; i++ {
+				pr, pg, pb := pixel(2*x+i%2, 2*y+i/2)
+				r, g, b = r+pr, g+pg, b+pb
+			}
+			e.srcU[y*e.cStride+x] = clip8((-9719*r - 19081*g + 28800*b + 1<<17 + 128<<18) >> 18)
+			e.srcV[y*e.cStride+x] = clip8((28800*r - 24116*g - 4684*b + 1<<17 + 128<<18) >> 18)
+		}
+	}
+
+	q := webpQuantizer
+	e.y1 = [2]int32{dequantTableDC[q], dequantTableAC[q]}
+	e.y2 = [2]int32{dequantTableDC[q] * 2, max(8, dequantTableAC[q]*155/100)}
+	e.uv = [2]int32{dequantTableDC[min(q, 117)], dequantTableAC[q]}
+
+	e.writeHeader()
+	return e
+}
+
+// Frame header in the first partition, section 9 of the RFC
+func (e *vp8Encoder) writeHeader() {
+	m := &e.modes
+	m.putBit(false, 128) // color space
+	m.putBit(false, 128) // clamping type
+	m.putBit(false, 128) // no segmentation
+	m.putBit(false, 128) // normal loop filter
+	m.putLiteral(webpFilterLevel, 6)
+	m.putLiteral(0, 3)   // sharpness
+	m.putBit(false, 128) // no filter deltas
+	m.putLiteral(0, 2)   // one token partition
+	m.putLiteral(webpQuantizer, 7)
+	for i := 0; i < 5; i++ {
+		m.putBit(false, 128) // no quantizer deltas
+	}
+	m.putBit(false, 128) // refresh entropy probabilities
+	for i := range tokenProbUpdateProb {
+		for j := range tokenProbUpdateProb[i] {
+			for k := range tokenProbUpdateProb[i][j] {
+				for l := range tokenProbUpdateProb[i][j][k] {
+					m.putBit(false, tokenProbUpdateProb[i][j][k][l])
+				}
+			}
+		}
+	}
+	m.putBit(false, 128) // no macroblock skip flags
+}
+
+func (e *vp8Encoder) encodeMacroblock(mbx, mby int) {
+	var yPred, uPred, vPred [256]uint8
+
+	// the modes with the smallest prediction error
+	yMode, best := predDC, -1
+	for mode := predDC; mode <= predHE; mode++ {
+		var pred [256]uint8
+		predict(&pred, e.recY, e.yStride, mbx, mby, 16, mode)
+		if sse := blockSSE(&pred, e.srcY, e.yStride, mbx*16, mby*16, 16); best < 0 || sse < best {
+			yMode, best, yPred = mode, sse, pred
+		}
+	}
+	uvMode, best := predDC, -1
+	for mode := predDC; mode <= predHE; mode++ {
+		var u, v [256]uint8
+		predict(&u, e.recU, e.cStride, mbx, mby, 8, mode)
+		predict(&v, e.recV, e.cStride, mbx, mby, 8, mode)
+		sse := blockSSE(&u, e.srcU, e.cStride, mbx*8, mby*8, 8) + blockSSE(&v, e.srcV, e.cStride, mbx*8, mby*8, 8)
+		if best < 0 || sse < best {
+			uvMode, best, uPred, vPred = mode, sse, u, v
+		}
+	}
+
+	m := &e.modes
+	m.putBit(true, 145) // 16x16 luma prediction
+	switch yMode {
+	case predDC:
+		m.putBit(false, 156)
+		m.putBit(false, 163)
+	case predVE:
+		m.putBit(false, 156)
+		m.putBit(true, 163)
+	case predHE:
+		m.putBit(true, 156)
+		m.putBit(false, 128)
+	case predTM:
+		m.putBit(true, 156)
+		m.putBit(true, 128)
+	}
+	switch uvMode {
+	case predDC:
+		m.putBit(false, 142)
+	case predVE:
+		m.putBit(true, 142)
+		m.putBit(false, 114)
+	case predHE:
+		m.putBit(true, 142)
+		m.putBit(true, 114)
+		m.putBit(false, 183)
+	case predTM:
+		m.putBit(true, 142)
+		m.putBit(true, 114)
+		m.putBit(true, 183)
+	}
+
+	top := &e.top[mbx]
+
+	// luma, the DC coefficients of the 16 blocks go through the WHT
+	var levels [16][16]int32
+	var dc [16]int32
+	for n := 0; n < 16; n++ {
+		var coeffs [16]int32
+		x, y := mbx*16+n%4*4, mby*16+n/4*4
+		residual(&coeffs, e.srcY, e.yStride, x, y, yPred[:], 16, n%4*4, n/4*4)
+		forwardDCT(&coeffs)
+		dc[n] = coeffs[0]
+		for i := 1; i < 16; i++ {
+			levels[n][i] = quantize(coeffs[i], e.y1[1], false)
+		}
+	}
+	forwardWHT(&dc)
+	var y2Levels [16]int32
+	for i := range dc {
+		y2Levels[i] = quantize(dc[i], e.y2[min(i, 1)], true)
+	}
+	nz := e.putCoeffs(planeY2, e.left.y2+top.y2, 0, &y2Levels)
+	e.left.y2, top.y2 = nz, nz
+	for y := 0; y < 4; y++ {
+		nz := e.left.y[y]
+		for x := 0; x < 4; x++ {
+			nz = e.putCoeffs(planeY1WithY2, nz+top.y[x], 1, &levels[y*4+x])
+			top.y[x] = nz
+		}
+		e.left.y[y] = nz
+	}
+
+	// reconstruct as the decoder does, the next macroblocks predict from it
+	for i := range y2Levels {
+		dc[i] = y2Levels[i] * e.y2[min(i, 1)]
+	}
+	inverseWHT(&dc)
+	copyBlock(e.recY, e.yStride, mbx*16, mby*16, yPred[:], 16)
+	for n := 0; n < 16; n++ {
+		coeffs := levels[n]
+		coeffs[0] = dc[n]
+		for i := 1; i < 16; i++ {
+			coeffs[i] *= e.y1[1]
+		}
+		inverseDCTAdd(&coeffs, e.recY, e.yStride, mbx*16+n%4*4, mby*16+n/4*4)
+	}
+
+	e.encodeChroma(e.srcU, e.recU, &uPred, &e.left.u, &top.u, mbx, mby)
+	e.encodeChroma(e.srcV, e.recV, &vPred, &e.left.v, &top.v, mbx, mby)
+}
+
+func (e *vp8Encoder) encodeChroma(src, rec []uint8, pred *[256]uint8, left, top *[2]uint8, mbx, mby int) {
+	var levels [4][16]int32
+	for n := 0; n < 4; n++ {
+		var coeffs [16]int32
+		x, y := mbx*8+n%2*4, mby*8+n/2*4
+		residual(&coeffs, src, e.cStride, x, y, pred[:], 8, n%2*4, n/2*4)
+		forwardDCT(&coeffs)
+		for i := range coeffs {
+			levels[n][i] = quantize(coeffs[i], e.uv[min(i, 1)], i == 0)
+		}
+	}
+	for y := 0; y < 2; y++ {
+		nz := left[y]
+		for x := 0; x < 2; x++ {
+			nz = e.putCoeffs(planeUV, nz+top[x], 0, &levels[y*2+x])
+			top[x] = nz
+		}
+		left[y] = nz
+	}
+
+	copyBlock(rec, e.cStride, mbx*8, mby*8, pred[:], 8)
+	for n := 0; n < 4; n++ {
+		coeffs := levels[n]
+		for i := range coeffs {
+			coeffs[i] *= e.uv[min(i, 1)]
+		}
+		inverseDCTAdd(&coeffs, rec, e.cStride, mbx*8+n%2*4, mby*8+n/2*4)
+	}
+}
+
+// Codes the quantized coefficients of one 4x4 block, section 13 of the RFC.
+// Returns 1 when any coefficient was coded.
+func (e *vp8Encoder) putCoeffs(plane int, context uint8, first int, levels *[16]int32) uint8 {
+	t := &e.tokens
+	last := -1
+	for n := first; n < 16; n++ {
+		if levels[zigzag[n]] != 0 {
+			last = n
+		}
+	}
+	p := &defaultTokenProb[plane][bands[first]][context]
+	if last < 0 {
+		t.putBit(false, p[0])
+		return 0
+	}
+	t.putBit(true, p[0])
+	for n := first; n <= last; n++ {
+		level := levels[zigzag[n]]
+		v := level
+		if v < 0 {
+			v = -v
+		}
+		if v == 0 {
+			t.putBit(false, p[1])
+			p = &defaultTokenProb[plane][bands[n+1]][0]
+			continue
+		}
+		t.putBit(true, p[1])
+		if v == 1 {
+			t.putBit(false, p[2])
+			p = &defaultTokenProb[plane][bands[n+1]][1]
+		} else {
+			t.putBit(true, p[2])
+			switch {
+			case v <= 4:
+				t.putBit(false, p[3])
+				if v == 2 {
+					t.putBit(false, p[4])
+				} else {
+					t.putBit(true, p[4])
+					t.putBit(v == 4, p[5])
+				}
+			case v <= 10:
+				t.putBit(true, p[3])
+				t.putBit(false, p[6])
+				if v <= 6 {
+					t.putBit(false, p[7])
+					t.putBit(v == 6, 159)
+				} else {
+					t.putBit(true, p[7])
+					t.putBit((v-7)&2 != 0, 165)
+					t.putBit((v-7)&1 != 0, 145)
+				}
+			default:
+				t.putBit(true, p[3])
+				t.putBit(true, p[6])
+				cat := 3
+				switch {
+				case v <= 18:
+					cat = 0
+				case v <= 34:
+					cat = 1
+				case v <= 66:
+					cat = 2
+				}
+				t.putBit(cat >= 2, p[8])
+				t.putBit(cat%2 == 1, p[9+cat/2])
+				extra := v - (3 + 8<<cat)
+				for i, prob := range cat3456[cat] {
+					t.putBit(extra>>(len(cat3456[cat])-1-i)&1 != 0, prob)
+				}
+			}
+			p = &defaultTokenProb[plane][bands[n+1]][2]
+		}
+		t.putBit(level < 0, 128)
+		if n < 15 {
+			t.putBit(n != last, p[0])
+		}
+	}
+	return 1
+}
+
+// Predicts a size x size block from the reconstructed pixels above and left
+// of it. Outside the frame the decoder assumes 127 above and 129 to the left.
+func predict(pred *[256]uint8, rec []uint8, stride, mbx, mby, size, mode int) {
+	x0, y0 := mbx*size, mby*size
+	var top, left [16]int32
+	var corner int32
+	for i := 0; i < size; i++ {
+		top[i], left[i] = 127, 129
+		if mby > 0 {
+			top[i] = int32(rec[(y0-1)*stride+x0+i])
+		}
+		if mbx > 0 {
+			left[i] = int32(rec[(y0+i)*stride+x0-1])
+		}
+	}
+	switch {
+	case mby == 0:
+		corner = 127
+	case mbx == 0:
+		corner = 129
+	default:
+		corner = int32(rec[(y0-1)*stride+x0-1])
+	}
+
+	shift := 3
+	if size == 16 {
+		shift = 4
+	}
+	var dc int32 = 128
+	switch {
+	case mbx > 0 && mby > 0:
+		var sum int32
+		for i := 0; i < size; i++ {
+			sum += top[i] + left[i]
+		}
+		dc = (sum + int32(size)) >> (shift + 1)
+	case mbx > 0:
+		var sum int32
+		for i := 0; i < size; i++ {
+			sum += left[i]
+		}
+		dc = (sum + int32(size/2)) >> shift
+	case mby > 0:
+		var sum int32
+		for i := 0; i < size; i++ {
+			sum += top[i]
+		}
+		dc = (sum + int32(size/2)) >> shift
+	}
+
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			var value int32
+			switch mode {
+			case predDC:
+				value = dc
+			case predTM:
+				value = left[y] + top[x] - corner
+			case predVE:
+				value = top[x]
+			case predHE:
+				value = left[y]
+			}
+			pred[y*size+x] = clip8(value)
+		}
+	}
+}
+
+func blockSSE(pred *[256]uint8, src []uint8, stride, x0, y0, size int) int {
+	sse := 0
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			d := int(src[(y0+y)*stride+x0+x]) - int(pred[y*size+x])
+			sse += d * d
+		}
+	}
+	return sse
+}
+
+// Source minus prediction for the 4x4 block at x, y, px, py within the prediction
+func residual(out *[16]int32, src []uint8, stride, x, y int, pred []uint8, size, px, py int) {
+	for j := 0; j < 4; j++ {
+		for i := 0; i < 4; i++ {
+			out[j*4+i] = int32(src[(y+j)*stride+x+i]) - int32(pred[(py+j)*size+px+i])
+		}
+	}
+}
+
+func copyBlock(dst []uint8, stride, x0, y0 int, src []uint8, size int) {
+	for y := 0; y < size; y++ {
+		copy(dst[(y0+y)*stride+x0:], src[y*size:(y+1)*size])
+	}
+}
+
+// Rounds DC coefficients to the nearest step and AC coefficients towards
+// zero a little, small AC coefficients cost more bits than they are worth
+func quantize(coeff, step int32, dc bool) int32 {
+	bias := step / 3
+	if dc {
+		bias = step / 2
+	}
+	level := coeff
+	if level < 0 {
+		level = -level
+	}
+	level = min((level+bias)/step, 2048)
+	if coeff < 0 {
+		return -level
+	}
+	return level
+}
+
+// Forward transforms as in the libvpx encoder, the inverses are the decoder's
+func forwardDCT(block *[16]int32) {
+	var tmp [16]int32
+	for i := 0; i < 4; i++ {
+		in := block[i*4 : i*4+4]
+		a := (in[0] + in[3]) * 8
+		b := (in[1] + in[2]) * 8
+		c := (in[1] - in[2]) * 8
+		d := (in[0] - in[3]) * 8
+		tmp[i*4+0] = a + b
+		tmp[i*4+2] = a - b
+		tmp[i*4+1] = (c*2217 + d*5352 + 14500) >> 12
+		tmp[i*4+3] = (d*2217 - c*5352 + 7500) >> 12
+	}
+	for i := 0; i < 4; i++ {
+		a := tmp[i] + tmp[12+i]
+		b := tmp[4+i] + tmp[8+i]
+		c := tmp[4+i] - tmp[8+i]
+		d := tmp[i] - tmp[12+i]
+		block[i] = (a + b + 7) >> 4
+		block[8+i] = (a - b + 7) >> 4
+		block[4+i] = (c*2217 + d*5352 + 12000) >> 16
+		if d != 0 {
+			block[4+i]++
+		}
+		block[12+i] = (d*2217 - c*5352 + 51000) >> 16
+	}
+}
+
+func forwardWHT(block *[16]int32) {
+	var tmp [16]int32
+	for i := 0; i < 4; i++ {
+		in := block[i*4 : i*4+4]
+		a := (in[0] + in[2]) * 4
+		d := (in[1] + in[3]) * 4
+		c := (in[1] - in[3]) * 4
+		b := (in[0] - in[2]) * 4
+		tmp[i*4+0] = a + d
+		if a != 0 {
+			tmp[i*4+0]++
+		}
+		tmp[i*4+1] = b + c
+		tmp[i*4+2] = b - c
+		tmp[i*4+3] = a - d
+	}
+	for i := 0; i < 4; i++ {
+		a := tmp[i] + tmp[8+i]
+		d := tmp[4+i] + tmp[12+i]
+		c := tmp[4+i] - tmp[12+i]
+		b := tmp[i] - tmp[8+i]
+		out := [4]int32{a + d, b + c, b - c, a - d}
+		for j, v := range out {
+			if v < 0 {
+				v++
+			}
+			block[j*4+i] = (v + 3) >> 3
+		}
+	}
+}
+
+func inverseWHT(block *[16]int32) {
+	var m [16]int32
+	for i := 0; i < 4; i++ {
+		a0 := block[i] + block[12+i]
+		a1 := block[4+i] + block[8+i]
+		a2 := block[4+i] - block[8+i]
+		a3 := block[i] - block[12+i]
+		m[i] = a0 + a1
+		m[8+i] = a0 - a1
+		m[4+i] = a3 + a2
+		m[12+i] = a3 - a2
+	}
+	for i := 0; i < 4; i++ {
+		dc := m[i*4] + 3
+		a0 := dc + m[i*4+3]
+		a1 := m[i*4+1] + m[i*4+2]
+		a2 := m[i*4+1] - m[i*4+2]
+		a3 := dc - m[i*4+3]
+		block[i*4+0] = (a0 + a1) >> 3
+		block[i*4+1] = (a3 + a2) >> 3
+		block[i*4+2] = (a0 - a1) >> 3
+		block[i*4+3] = (a3 - a2) >> 3
+	}
+}
+
+// Adds the inverse DCT of the dequantized coefficients to the prediction
+// already in dst
+func inverseDCTAdd(coeffs *[16]int32, dst []uint8, stride, x0, y0 int) {
+	const (
+		c1 = 85627 // 65536 * cos(pi/8) * sqrt(2)
+		c2 = 35468 // 65536 * sin(pi/8) * sqrt(2)
+	)
+	var m [4][4]int32
+	for i := 0; i < 4; i++ {
+		a := coeffs[i] + coeffs[8+i]
+		b := coeffs[i] - coeffs[8+i]
+		c := (coeffs[4+i]*c2)>>16 - (coeffs[12+i]*c1)>>16
+		d := (coeffs[4+i]*c1)>>16 + (coeffs[12+i]*c2)>>16
+		m[i] = [4]int32{a + d, b + c, b - c, a - d}
+	}
+	for j := 0; j < 4; j++ {
+		dc := m[0][j] + 4
+		a := dc + m[2][j]
+		b := dc - m[2][j]
+		c := (m[1][j]*c2)>>16 - (m[3][j]*c1)>>16
+		d := (m[1][j]*c1)>>16 + (m[3][j]*c2)>>16
+		row := dst[(y0+j)*stride+x0 : (y0+j)*stride+x0+4]
+		for i, v := range [4]int32{a + d, b + c, b - c, a - d} {
+			row[i] = clip8(int32(row[i]) + v>>3)
+		}
+	}
+}
+
+func clip8[T int | int32](v T) uint8 {
+	if v < 0 {
+		return 0
+	}
+	if v > 255 {
+		return 255
+	}
+	return uint8(v)
+}
+
+// Boolean entropy encoder, section 7 of the RFC
+type vp8BoolEncoder struct {
+	buf      []byte
+	rng      uint32
+	bottom   uint32
+	bitCount int
+}
+
+func (b *vp8BoolEncoder) putBit(bit bool, prob uint8) {
+	if b.rng == 0 {
+		b.rng, b.bitCount = 255, 24
+	}
+	split := 1 + (b.rng-1)*uint32(prob)>>8
+	if bit {
+		b.bottom += split
+		b.rng -= split
+	} else {
+		b.rng = split
+	}
+	for b.rng < 128 {
+		b.rng <<= 1
+		if b.bottom&(1<<31) != 0 {
+			// carry into the bytes already written
+			i := len(b.buf) - 1
+			for ; i >= 0 && b.buf[i] == 0xff; i-- {
+				b.buf[i] = 0
+			}
+			b.buf[i]++
+		}
+		b.bottom <<= 1
+		b.bitCount--
+		if b.bitCount == 0 {
+			b.buf = append(b.buf, byte(b.bottom>>24))
+			b.bottom &= 0xffffff
+			b.bitCount = 8
+		}
+	}
+}
+
+func (b *vp8BoolEncoder) putLiteral(value, bits int) {
+	for i := bits - 1; i >= 0; i-- {
+		b.putBit(value>>i&1 != 0, 128)
+	}
+}
+
+// Pushes the pending bits out
+func (b *vp8BoolEncoder) finish() []byte {
+	for i := 0; i < 32; i++ {
+		b.putBit(false, 128)
+	}
+	return b.buf
+}
+
+// Dequantization steps by quantizer index, section 14.1 of the RFC
+var (
+	dequantTableDC = [128]int32{
+		4, 5, 6, 7, 8, 9, 10, 10,
+		11, 12, 13, 14, 15, 16, 17, 17,
+		18, 19, 20, 20, 21, 21, 22, 22,
+		23, 23, 24, 25, 25, 26, 27, 28,
+		29, 30, 31, 32, 33, 34, 35, 36,
+		37, 37, 38, 39, 40, 41, 42, 43,
+		44, 45, 46, 46, 47, 48, 49, 50,
+		51, 52, 53, 54, 55, 56, 57, 58,
+		59, 60, 61, 62, 63, 64, 65, 66,
+		67, 68, 69, 70, 71, 72, 73, 74,
+		75, 76, 76, 77, 78, 79, 80, 81,
+		82, 83, 84, 85, 86, 87, 88, 89,
+		91, 93, 95, 96, 98, 100, 101, 102,
+		104, 106, 108, 110, 112, 114, 116, 118,
+		122, 124, 126, 128, 130, 132, 134, 136,
+		138, 140, 143, 145, 148, 151, 154, 157,
+	}
+	dequantTableAC = [128]int32{
+		4, 5, 6, 7, 8, 9, 10, 11,
+		12, 13, 14, 15, 16, 17, 18, 19,
+		20, 21, 22, 23, 24, 25, 26, 27,
+		28, 29, 30, 31, 32, 33, 34, 35,
+		36, 37, 38, 39, 40, 41, 42, 43,
+		44, 45, 46, 47, 48, 49, 50, 51,
+		52, 53, 54, 55, 56, 57, 58, 60,
+		62, 64, 66, 68, 70, 72, 74, 76,
+		78, 80, 82, 84, 86, 88, 90, 92,
+		94, 96, 98, 100, 102, 104, 106, 108,
+		110, 112, 114, 116, 119, 122, 125, 128,
+		131, 134, 137, 140, 143, 146, 149, 152,
+		155, 158, 161, 164, 167, 170, 173, 177,
+		181, 185, 189, 193, 197, 201, 205, 209,
+		213, 217, 221, 225, 229, 234, 239, 245,
+		249, 254, 259, 264, 269, 274, 279, 284,
+	}
+)
+
+// Probabilities the token probability updates are coded with, section 13.4
+var tokenProbUpdateProb = [4][8][3][11]uint8{
+	{
+		{
+			{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+			{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+			{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+		},
+		{
+			{176, 246, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+			{223, 241, 252, 255, 255, 255, 255, 255, 255, 255, 255},
+			{249, 253, 253, 255, 255, 255, 255, 255, 255, 255, 255},
+		},
+		{
+			{255, 244, 252, 255, 255, 255, 255, 255, 255, 255, 255},
+			{234, 254, 254, 255, 255, 255, 255, 255, 255, 255, 255},
+			{253, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+		},
+		{
+			{255, 246, 254, 255, 255, 255, 255, 255, 255, 255, 255},
+			{239, 253, 254, 255, 255, 255, 255, 255, 255, 255, 255},
+			{254, 255, 254, 255, 255, 255, 255, 255, 255, 255, 255},
+		},
+		{
+			{255, 248, 254, 255, 255, 255, 255, 255, 255, 255, 255},
+			{251, 255, 254, 255, 255, 255, 255, 255, 255, 255, 255},
+			{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+		},
+		{
+			{255, 253, 254, 255, 255, 255, 255, 255, 255, 255, 255},
+			{251, 254, 254, 255, 255, 255, 255, 255, 255, 255, 255},
+			{254, 255, 254, 255, 255, 255, 255, 255, 255, 255, 255},
+		},
+		{
+			{255, 254, 253, 255, 254, 255, 255, 255, 255, 255, 255},
+			{250, 255, 254, 255, 254, 255, 255, 255, 255, 255, 255},
+			{254, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+		},
+		{
+			{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+			{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+			{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+		},
+	},
+	{
+		{
+			{217, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+			{225, 252, 241, 253, 255, 255, 254, 255, 255, 255, 255},
+			{234, 250, 241, 250, 253, 255, 253, 254, 255, 255, 255},
+		},
+		{
+			{255, 254, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+			{223, 254, 254, 255, 255, 255, 255, 255, 255, 255, 255},
+			{238, 253, 254, 254, 255, 255, 255, 255, 255, 255, 255},
+		},
+		{
+			{255, 248, 254, 255, 255, 255, 255, 255, 255, 255, 255},
+			{249, 254, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+			{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+		},
+		{
+			{255, 253, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+			{247, 254, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+			{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+		},
+		{
+			{255, 253, 254, 255, 255, 255, 255, 255, 255, 255, 255},
+			{252, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+			{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+		},
+		{
+			{255, 254, 254, 255, 255, 255, 255, 255, 255, 255, 255},
+			{253, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+			{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+		},
+		{
+			{255, 254, 253, 255, 255, 255, 255, 255, 255, 255, 255},
+			{250, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+			{254, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+		},
+		{
+			{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+			{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+			{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+		},
+	},
+	{
+		{
+			{186, 251, 250, 255, 255, 255, 255, 255, 255, 255, 255},
+			{234, 251, 244, 254, 255, 255, 255, 255, 255, 255, 255},
+			{251, 251, 243, 253, 254, 255, 254, 255, 255, 255, 255},
+		},
+		{
+			{255, 253, 254, 255, 255, 255, 255, 255, 255, 255, 255},
+			{236, 253, 254, 255, 255, 255, 255, 255, 255, 255, 255},
+			{251, 253, 253, 254, 254, 255, 255, 255, 255, 255, 255},
+		},
+		{
+			{255, 254, 254, 255, 255, 255, 255, 255, 255, 255, 255},
+			{254, 254, 254, 255, 255, 255, 255, 255, 255, 255, 255},
+			{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+		},
+		{
+			{255, 254, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+			{254, 254, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+			{254, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+		},
+		{
+			{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+			{254, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+			{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+		},
+		{
+			{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+			{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+			{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+		},
+		{
+			{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+			{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+			{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+		},
+		{
+			{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+			{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+			{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+		},
+	},
+	{
+		{
+			{248, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+			{250, 254, 252, 254, 255, 255, 255, 255, 255, 255, 255},
+			{248, 254, 249, 253, 255, 255, 255, 255, 255, 255, 255},
+		},
+		{
+			{255, 253, 253, 255, 255, 255, 255, 255, 255, 255, 255},
+			{246, 253, 253, 255, 255, 255, 255, 255, 255, 255, 255},
+			{252, 254, 251, 254, 254, 255, 255, 255, 255, 255, 255},
+		},
+		{
+			{255, 254, 252, 255, 255, 255, 255, 255, 255, 255, 255},
+			{248, 254, 253, 255, 255, 255, 255, 255, 255, 255, 255},
+			{253, 255, 254, 254, 255, 255, 255, 255, 255, 255, 255},
+		},
+		{
+			{255, 251, 254, 255, 255, 255, 255, 255, 255, 255, 255},
+			{245, 251, 254, 255, 255, 255, 255, 255, 255, 255, 255},
+			{253, 253, 254, 255, 255, 255, 255, 255, 255, 255, 255},
+		},
+		{
+			{255, 251, 253, 255, 255, 255, 255, 255, 255, 255, 255},
+			{252, 253, 254, 255, 255, 255, 255, 255, 255, 255, 255},
+			{255, 254, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+		},
+		{
+			{255, 252, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+			{249, 255, 254, 255, 255, 255, 255, 255, 255, 255, 255},
+			{255, 255, 254, 255, 255, 255, 255, 255, 255, 255, 255},
+		},
+		{
+			{255, 255, 253, 255, 255, 255, 255, 255, 255, 255, 255},
+			{250, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+			{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+		},
+		{
+			{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+			{254, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+			{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
+		},
+	},
+}
+
+// Token probabilities, section 13.5. The encoder never updates them.
+var defaultTokenProb = [4][8][3][11]uint8{
+	{
+		{
+			{128, 128, 128, 128, 128, 128, 128, 128, 128, 128, 128},
+			{128, 128, 128, 128, 128, 128, 128, 128, 128, 128, 128},
+			{128, 128, 128, 128, 128, 128, 128, 128, 128, 128, 128},
+		},
+		{
+			{253, 136, 254, 255, 228, 219, 128, 128, 128, 128, 128},
+			{189, 129, 242, 255, 227, 213, 255, 219, 128, 128, 128},
+			{106, 126, 227, 252, 214, 209, 255, 255, 128, 128, 128},
+		},
+		{
+			{1, 98, 248, 255, 236, 226, 255, 255, 128, 128, 128},
+			{181, 133, 238, 254, 221, 234, 255, 154, 128, 128, 128},
+			{78, 134, 202, 247, 198, 180, 255, 219, 128, 128, 128},
+		},
+		{
+			{1, 185, 249, 255, 243, 255, 128, 128, 128, 128, 128},
+			{184, 150, 247, 255, 236, 224, 128, 128, 128, 128, 128},
+			{77, 110, 216, 255, 236, 230, 128, 128, 128, 128, 128},
+		},
+		{
+			{1, 101, 251, 255, 241, 255, 128, 128, 128, 128, 128},
+			{170, 139, 241, 252, 236, 209, 255, 255, 128, 128, 128},
+			{37, 116, 196, 243, 228, 255, 255, 255, 128, 128, 128},
+		},
+		{
+			{1, 204, 254, 255, 245, 255, 128, 128, 128, 128, 128},
+			{207, 160, 250, 255, 238, 128, 128, 128, 128, 128, 128},
+			{102, 103, 231, 255, 211, 171, 128, 128, 128, 128, 128},
+		},
+		{
+			{1, 152, 252, 255, 240, 255, 128, 128, 128, 128, 128},
+			{177, 135, 243, 255, 234, 225, 128, 128, 128, 128, 128},
+			{80, 129, 211, 255, 194, 224, 128, 128, 128, 128, 128},
+		},
+		{
+			{1, 1, 255, 128, 128, 128, 128, 128, 128, 128, 128},
+			{246, 1, 255, 128, 128, 128, 128, 128, 128, 128, 128},
+			{255, 128, 128, 128, 128, 128, 128, 128, 128, 128, 128},
+		},
+	},
+	{
+		{
+			{198, 35, 237, 223, 193, 187, 162, 160, 145, 155, 62},
+			{131, 45, 198, 221, 172, 176, 220, 157, 252, 221, 1},
+			{68, 47, 146, 208, 149, 167, 221, 162, 255, 223, 128},
+		},
+		{
+			{1, 149, 241, 255, 221, 224, 255, 255, 128, 128, 128},
+			{184, 141, 234, 253, 222, 220, 255, 199, 128, 128, 128},
+			{81, 99, 181, 242, 176, 190, 249, 202, 255, 255, 128},
+		},
+		{
+			{1, 129, 232, 253, 214, 197, 242, 196, 255, 255, 128},
+			{99, 121, 210, 250, 201, 198, 255, 202, 128, 128, 128},
+			{23, 91, 163, 242, 170, 187, 247, 210, 255, 255, 128},
+		},
+		{
+			{1, 200, 246, 255, 234, 255, 128, 128, 128, 128, 128},
+			{109, 178, 241, 255, 231, 245, 255, 255, 128, 128, 128},
+			{44, 130, 201, 253, 205, 192, 255, 255, 128, 128, 128},
+		},
+		{
+			{1, 132, 239, 251, 219, 209, 255, 165, 128, 128, 128},
+			{94, 136, 225, 251, 218, 190, 255, 255, 128, 128, 128},
+			{22, 100, 174, 245, 186, 161, 255, 199, 128, 128, 128},
+		},
+		{
+			{1, 182, 249, 255, 232, 235, 128, 128, 128, 128, 128},
+			{124, 143, 241, 255, 227, 234, 128, 128, 128, 128, 128},
+			{35, 77, 181, 251, 193, 211, 255, 205, 128, 128, 128},
+		},
+		{
+			{1, 157, 247, 255, 236, 231, 255, 255, 128, 128, 128},
+			{121, 141, 235, 255, 225, 227, 255, 255, 128, 128, 128},
+			{45, 99, 188, 251, 195, 217, 255, 224, 128, 128, 128},
+		},
+		{
+			{1, 1, 251, 255, 213, 255, 128, 128, 128, 128, 128},
+			{203, 1, 248, 255, 255, 128, 128, 128, 128, 128, 128},
+			{137, 1, 177, 255, 224, 255, 128, 128, 128, 128, 128},
+		},
+	},
+	{
+		{
+			{253, 9, 248, 251, 207, 208, 255, 192, 128, 128, 128},
+			{175, 13, 224, 243, 193, 185, 249, 198, 255, 255, 128},
+			{73, 17, 171, 221, 161, 179, 236, 167, 255, 234, 128},
+		},
+		{
+			{1, 95, 247, 253, 212, 183, 255, 255, 128, 128, 128},
+			{239, 90, 244, 250, 211, 209, 255, 255, 128, 128, 128},
+			{155, 77, 195, 248, 188, 195, 255, 255, 128, 128, 128},
+		},
+		{
+			{1, 24, 239, 251, 218, 219, 255, 205, 128, 128, 128},
+			{201, 51, 219, 255, 196, 186, 128, 128, 128, 128, 128},
+			{69, 46, 190, 239, 201, 218, 255, 228, 128, 128, 128},
+		},
+		{
+			{1, 191, 251, 255, 255, 128, 128, 128, 128, 128, 128},
+			{223, 165, 249, 255, 213, 255, 128, 128, 128, 128, 128},
+			{141, 124, 248, 255, 255, 128, 128, 128, 128, 128, 128},
+		},
+		{
+			{1, 16, 248, 255, 255, 128, 128, 128, 128, 128, 128},
+			{190, 36, 230, 255, 236, 255, 128, 128, 128, 128, 128},
+			{149, 1, 255, 128, 128, 128, 128, 128, 128, 128, 128},
+		},
+		{
+			{1, 226, 255, 128, 128, 128, 128, 128, 128, 128, 128},
+			{247, 192, 255, 128, 128, 128, 128, 128, 128, 128, 128},
+			{240, 128, 255, 128, 128, 128, 128, 128, 128, 128, 128},
+		},
+		{
+			{1, 134, 252, 255, 255, 128, 128, 128, 128, 128, 128},
+			{213, 62, 250, 255, 255, 128, 128, 128, 128, 128, 128},
+			{55, 93, 255, 128, 128, 128, 128, 128, 128, 128, 128},
+		},
+		{
+			{128, 128, 128, 128, 128, 128, 128, 128, 128, 128, 128},
+			{128, 128, 128, 128, 128, 128, 128, 128, 128, 128, 128},
+			{128, 128, 128, 128, 128, 128, 128, 128, 128, 128, 128},
+		},
+	},
+	{
+		{
+			{202, 24, 213, 235, 186, 191, 220, 160, 240, 175, 255},
+			{126, 38, 182, 232, 169, 184, 228, 174, 255, 187, 128},
+			{61, 46, 138, 219, 151, 178, 240, 170, 255, 216, 128},
+		},
+		{
+			{1, 112, 230, 250, 199, 191, 247, 159, 255, 255, 128},
+			{166, 109, 228, 252, 211, 215, 255, 174, 128, 128, 128},
+			{39, 77, 162, 232, 172, 180, 245, 178, 255, 255, 128},
+		},
+		{
+			{1, 52, 220, 246, 198, 199, 249, 220, 255, 255, 128},
+			{124, 74, 191, 243, 183, 193, 250, 221, 255, 255, 128},
+			{24, 71, 130, 219, 154, 170, 243, 182, 255, 255, 128},
+		},
+		{
+			{1, 182, 225, 249, 219, 240, 255, 224, 128, 128, 128},
+			{149, 150, 226, 252, 216, 205, 255, 171, 128, 128, 128},
+			{28, 108, 170, 242, 183, 194, 254, 223, 255, 255, 128},
+		},
+		{
+			{1, 81, 230, 252, 204, 203, 255, 192, 128, 128, 128},
+			{123, 102, 209, 247, 188, 196, 255, 233, 128, 128, 128},
+			{20, 95, 153, 243, 164, 173, 255, 203, 128, 128, 128},
+		},
+		{
+			{1, 222, 248, 255, 216, 213, 128, 128, 128, 128, 128},
+			{168, 175, 246, 252, 235, 205, 255, 255, 128, 128, 128},
+			{47, 116, 215, 255, 211, 212, 255, 255, 128, 128, 128},
+		},
+		{
+			{1, 121, 236, 253, 212, 214, 255, 255, 128, 128, 128},
+			{141, 84, 213, 252, 201, 202, 255, 219, 128, 128, 128},
+			{42, 80, 160, 240, 162, 185, 255, 205, 128, 128, 128},
+		},
+		{
+			{1, 1, 255, 128, 128, 128, 128, 128, 128, 128, 128},
+			{244, 1, 255, 128, 128, 128, 128, 128, 128, 128, 128},
+			{238, 1, 255, 128, 128, 128, 128, 128, 128, 128, 128},
+		},
+	},
+}
@@ -0,0 +1,66 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"golang.org/x/image/webp"
+)
+
+// Smooth gradients with a few hard edges, roughly what photos look like to the encoder
+func testImage(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := color.RGBA{uint8(x * 255 / width), uint8(y * 255 / height), 128, 255}
+			if (x/40+y/30)%3 == 0 {
+				c = color.RGBA{230, 60, 40, 255}
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestEncodeWebpDecodes(t *testing.T) {
+	for _, size := range []image.Point{{1, 1}, {17, 9}, {400, 301}} {
+		img := testImage(size.X, size.Y)
+
+		var encoded bytes.Buffer
+		if err := encodeWebp(&encoded, img); err != nil {
+			t.Fatalf("%v: encodeWebp: %v", size, err)
+		}
+		decoded, err := webp.Decode(&encoded)
+		if err != nil {
+			t.Fatalf("%v: cannot decode: %v", size, err)
+		}
+		if decoded.Bounds().Size() != size {
+			t.Fatalf("%v: decoded size %v", size, decoded.Bounds().Size())
+		}
+
+		// compare the luma, the decoder converts colors with other coefficients
+		ycbcr, ok := decoded.(*image.YCbCr)
+		if !ok {
+			t.Fatalf("%v: decoded %T", size, decoded)
+		}
+		var sse float64
+		for y := 0; y < size.Y; y++ {
+			for x := 0; x < size.X; x++ {
+				c := img.RGBAAt(x, y)
+				want := (16839*int(c.R) + 33059*int(c.G) + 6420*int(c.B) + 16<<16 + 1<<15) >> 16
+				d := float64(want) - float64(ycbcr.Y[ycbcr.YOffset(x, y)])
+				sse += d * d
+			}
+		}
+		psnr := math.Inf(1)
+		if sse > 0 {
+			psnr = 10 * math.Log10(255*255*float64(size.X*size.Y)/sse)
+		}
+		if psnr < 35 {
+			t.Errorf("%v: luma PSNR %.1f dB, want at least 35 dB", size, psnr)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Delivers email into a Maildir on the local disk instead of sending it, for
+// development. Any mail client supporting Maildir can open the directory.
+type LogMailSender struct {
+	dir    string
+	from   string
+	logger *slog.Logger
+}
+
+func NewLogMailSender(dir, from string, logger *slog.Logger) (*LogMailSender, error) {
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			return nil, fmt.Errorf("error creating maildir: %v", err)
+		}
+	}
+
+	return &LogMailSender{
+		dir:    dir,
+		from:   from,
+		logger: logger,
+	}, nil
+}
+
+// Writes to tmp first and moves the file to new, so readers never see partial messages
+func (s *LogMailSender) Send(ctx context.Context, message Message) error {
+	name := fmt.Sprintf("%d.%s.sportspazz", time.Now().Unix(), uuid.New().String())
+	tmpPath := filepath.Join(s.dir, "tmp", name)
+	newPath := filepath.Join(s.dir, "new", name)
+
+	if err := os.WriteFile(tmpPath, formatMessage(s.from, message), 0o644); err != nil {
+		return fmt.Errorf("error writing email to %s: %v", tmpPath, err)
+	}
+	if err := os.Rename(tmpPath, newPath); err != nil {
+		return fmt.Errorf("error delivering email to %s: %v", newPath, err)
+	}
+
+	s.logger.Info("email delivered to maildir", slog.String("to", message.To), slog.String("subject", message.Subject), slog.String("path", newPath))
+	return nil
+}
@@ -0,0 +1,18 @@
+package mail
+
+import (
+	"context"
+)
+
+// Email with a plain text body and an optional HTML alternative
+type Message struct {
+	To       string
+	Subject  string
+	Body     string
+	HtmlBody string
+}
+
+// Delivers outbound email
+type MailSender interface {
+	Send(ctx context.Context, message Message) error
+}
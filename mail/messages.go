@@ -0,0 +1,61 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/sportspazz/mail/templates"
+)
+
+func WelcomeMessage(ctx context.Context, to, siteUrl string) (Message, error) {
+	text := fmt.Sprintf("Welcome to Sportspazz!\n\n"+
+		"Thanks for joining! Sportspazz helps you find places to play your favourite sports and share the ones you know with others.\n\n"+
+		"Find places to play: %s/wheretoplay\n", siteUrl)
+
+	return newMessage(ctx, to, "Welcome to Sportspazz", text, templates.Welcome(siteUrl))
+}
+
+func VerifyEmailMessage(ctx context.Context, to, link string, expiresIn time.Duration) (Message, error) {
+	hours := int(expiresIn.Hours())
+	text := fmt.Sprintf("Please confirm your email address so you can start adding places by opening the link below:\n\n%s\n\n"+
+		"The link expires in %d hours.\n", link, hours)
+
+	return newMessage(ctx, to, "Verify your Sportspazz email address", text, templates.VerifyEmail(link, hours))
+}
+
+func ResetPasswordMessage(ctx context.Context, to, link string, expiresIn time.Duration) (Message, error) {
+	minutes := int(expiresIn.Minutes())
+	text := fmt.Sprintf("Someone asked to reset the password of your Sportspazz account.\n\n"+
+		"Open the link below to choose a new password:\n\n%s\n\n"+
+		"The link expires in %d minutes. If you did not ask for it, you can ignore this email.\n", link, minutes)
+
+	return newMessage(ctx, to, "Reset your Sportspazz password", text, templates.ResetPassword(link, minutes))
+}
+
+func PlaceApprovedMessage(ctx context.Context, to, placeName, placeUrl string) (Message, error) {
+	text := fmt.Sprintf("Good news! %s passed moderation and is now visible to everyone.\n\n"+
+		"See your place: %s\n", placeName, placeUrl)
+
+	return newMessage(ctx, to, "Your place was approved", text, templates.PlaceApproved(placeName, placeUrl))
+}
+
+type component interface {
+	Render(ctx context.Context, w io.Writer) error
+}
+
+func newMessage(ctx context.Context, to, subject, text string, html component) (Message, error) {
+	var htmlBody bytes.Buffer
+	if err := html.Render(ctx, &htmlBody); err != nil {
+		return Message{}, fmt.Errorf("error rendering %q email: %v", subject, err)
+	}
+
+	return Message{
+		To:       to,
+		Subject:  subject,
+		Body:     text,
+		HtmlBody: htmlBody.String(),
+	}, nil
+}
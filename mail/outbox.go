@@ -0,0 +1,121 @@
+package mail
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	outboxPollInterval = 10 * time.Second
+	outboxBatchSize    = 20
+	// time given to send a claimed message before another attempt is made
+	outboxLease       = 5 * time.Minute
+	outboxMaxAttempts = 8
+	outboxBaseBackoff = time.Minute
+	outboxMaxBackoff  = 6 * time.Hour
+)
+
+// MailSender persisting messages in the mail_outbox table, Run delivers them
+// through the underlying sender and retries failures with exponential backoff
+type Outbox struct {
+	store      *OutboxStore
+	mailSender MailSender
+	logger     *slog.Logger
+}
+
+func NewOutbox(store *OutboxStore, mailSender MailSender, logger *slog.Logger) *Outbox {
+	return &Outbox{
+		store:      store,
+		mailSender: mailSender,
+		logger:     logger,
+	}
+}
+
+func (o *Outbox) Send(ctx context.Context, message Message) error {
+	now := time.Now().UTC()
+	return o.store.CreateMessage(&OutboxMessage{
+		ID:            uuid.New().String(),
+		CreatedOn:     now,
+		UpdatedOn:     now,
+		Recipient:     message.To,
+		Subject:       message.Subject,
+		TextBody:      message.Body,
+		HtmlBody:      message.HtmlBody,
+		Status:        OutboxPending,
+		NextAttemptOn: now,
+	})
+}
+
+// Delivers queued messages until ctx is done
+func (o *Outbox) Run(ctx context.Context) {
+	ticker := time.NewTicker(outboxPollInterval)
+	defer ticker.Stop()
+
+	for {
+		o.deliverDueMessages(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (o *Outbox) deliverDueMessages(ctx context.Context) {
+	for {
+		messages, err := o.store.ClaimDueMessages(outboxBatchSize, outboxLease)
+		if err != nil {
+			o.logger.Error("not able to claim outbox messages", slog.Any("err", err))
+			return
+		}
+
+		for _, message := range messages {
+			o.deliver(ctx, message)
+		}
+		if len(messages) < outboxBatchSize || ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+func (o *Outbox) deliver(ctx context.Context, message OutboxMessage) {
+	err := o.mailSender.Send(ctx, Message{
+		To:       message.Recipient,
+		Subject:  message.Subject,
+		Body:     message.TextBody,
+		HtmlBody: message.HtmlBody,
+	})
+	if err == nil {
+		if err := o.store.MarkSent(message.ID); err != nil {
+			o.logger.Error("not able to mark email sent", slog.String("id", message.ID), slog.Any("err", err))
+		}
+		return
+	}
+
+	status, nextAttemptOn := OutboxPending, time.Now().UTC().Add(backoff(message.Attempts))
+	if message.Attempts >= outboxMaxAttempts {
+		status = OutboxFailed
+	}
+	o.logger.Warn("not able to send email",
+		slog.String("id", message.ID),
+		slog.Int("attempts", message.Attempts),
+		slog.String("status", string(status)),
+		slog.Any("err", err))
+
+	if err := o.store.MarkFailed(message.ID, status, nextAttemptOn, err.Error()); err != nil {
+		o.logger.Error("not able to reschedule email", slog.String("id", message.ID), slog.Any("err", err))
+	}
+}
+
+// 1, 2, 4, ... minutes after the given number of attempts, at most outboxMaxBackoff
+func backoff(attempts int) time.Duration {
+	delay := outboxBaseBackoff
+	for i := 1; i < attempts && delay < outboxMaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, outboxMaxBackoff)
+}
@@ -0,0 +1,100 @@
+package mail
+
+import (
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type OutboxStatus string
+
+const (
+	OutboxPending OutboxStatus = "pending"
+	OutboxSent    OutboxStatus = "sent"
+	OutboxFailed  OutboxStatus = "failed"
+)
+
+type OutboxMessage struct {
+	internalId    uint `gorm:"primaryKey"`
+	ID            string
+	CreatedOn     time.Time
+	UpdatedOn     time.Time
+	Recipient     string
+	Subject       string
+	TextBody      string
+	HtmlBody      string
+	Status        OutboxStatus
+	Attempts      int
+	NextAttemptOn time.Time
+	LastError     *string
+	SentOn        *time.Time
+}
+
+func (OutboxMessage) TableName() string {
+	return "mail_outbox"
+}
+
+type OutboxStore struct {
+	db     *gorm.DB
+	logger *slog.Logger
+}
+
+func NewOutboxStore(db *gorm.DB, logger *slog.Logger) *OutboxStore {
+	return &OutboxStore{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (s *OutboxStore) CreateMessage(message *OutboxMessage) error {
+	if err := s.db.Create(message).Error; err != nil {
+		s.logger.Error("not able to queue an email", slog.Any("err", err))
+		return err
+	}
+	return nil
+}
+
+// Claims due messages by pushing their next attempt out by lease, so other
+// instances skip them while they are being sent. Messages of a crashed
+// instance become due again once the lease expires.
+func (s *OutboxStore) ClaimDueMessages(limit int, lease time.Duration) ([]OutboxMessage, error) {
+	now := time.Now().UTC()
+
+	var messages []OutboxMessage
+	err := s.db.Raw(`
+		UPDATE mail_outbox SET attempts = attempts + 1, next_attempt_on = ?, updated_on = ?
+		WHERE internal_id IN (
+			SELECT internal_id FROM mail_outbox
+			WHERE status = ? AND next_attempt_on <= ?
+			ORDER BY next_attempt_on
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`, now.Add(lease), now, OutboxPending, now, limit).
+		Scan(&messages).Error
+
+	return messages, err
+}
+
+func (s *OutboxStore) MarkSent(id string) error {
+	now := time.Now().UTC()
+	return s.db.Model(&OutboxMessage{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status":     OutboxSent,
+			"sent_on":    now,
+			"updated_on": now,
+		}).Error
+}
+
+func (s *OutboxStore) MarkFailed(id string, status OutboxStatus, nextAttemptOn time.Time, lastError string) error {
+	return s.db.Model(&OutboxMessage{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status":          status,
+			"next_attempt_on": nextAttemptOn,
+			"last_error":      lastError,
+			"updated_on":      time.Now().UTC(),
+		}).Error
+}
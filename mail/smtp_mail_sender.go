@@ -0,0 +1,47 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"net"
+	netmail "net/mail"
+	"net/smtp"
+)
+
+// Sends email through an SMTP relay, authenticating when a username is set
+type SmtpMailSender struct {
+	host     string
+	port     string
+	username string
+	password string
+	from     string
+}
+
+func NewSmtpMailSender(host, port, username, password, from string) *SmtpMailSender {
+	return &SmtpMailSender{
+		host:     host,
+		port:     port,
+		username: username,
+		password: password,
+		from:     from,
+	}
+}
+
+func (s *SmtpMailSender) Send(ctx context.Context, message Message) error {
+	var auth smtp.Auth
+	if s.username != "" {
+		auth = smtp.PlainAuth("", s.username, s.password, s.host)
+	}
+
+	// the envelope sender is the bare address of the From header
+	sender, err := netmail.ParseAddress(s.from)
+	if err != nil {
+		return fmt.Errorf("invalid sender address %q: %v", s.from, err)
+	}
+
+	err = smtp.SendMail(net.JoinHostPort(s.host, s.port), auth, sender.Address, []string{message.To}, formatMessage(s.from, message))
+	if err != nil {
+		return fmt.Errorf("error sending email to %s: %v", message.To, err)
+	}
+	return nil
+}
@@ -6,10 +6,12 @@ import (
 	"log/slog"
 	"net/http"
 	"strings"
-	"time"
+	"sync"
 
-	"github.com/dgrijalva/jwt-go"
-	"github.com/sportspazz/api/client"
+	"github.com/sportspazz/api/web/templates"
+	"github.com/sportspazz/identity"
+	"github.com/sportspazz/service/session"
+	"github.com/sportspazz/service/user"
 	"github.com/sportspazz/utils"
 )
 
@@ -46,28 +48,31 @@ func ContentTypeHeaderMiddleWare(next http.Handler) http.Handler {
 	})
 }
 
-func AuthenticateMiddleWare(firebaseClient *client.FirebaseClient, logger *slog.Logger) func(http.Handler) http.Handler {
+func AuthenticateMiddleWare(sessionService *session.SessionService, identityProvider identity.IdentityProvider, userService *user.UserService, logger *slog.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			ctx := r.Context()
-			if idTokenCookie, _ := r.Cookie(string(utils.IdTokenKey)); idTokenCookie != nil {
-				token, err := firebaseClient.VerifyIDToken(idTokenCookie.Value)
-
-				if err != nil {
-					refreshToken(firebaseClient, w, r, logger)
-				} else if token.Valid {
-					ctx = updateContext(token, ctx)
+			ctx := context.WithValue(r.Context(), utils.LoginedKey, false)
+
+			if sessionCookie, _ := r.Cookie(string(utils.SessionKey)); sessionCookie != nil {
+				if s, err := sessionService.ResolveSession(sessionCookie.Value); err == nil {
+					ctx = sessionContext(s, ctx)
+				} else {
+					utils.ClearSessionCookie(w)
 				}
-			} else {
-				ctx = context.WithValue(ctx, utils.LoginedKey, false)
+			} else if s := migrateTokenCookies(sessionService, identityProvider, w, r, logger); s != nil {
+				ctx = sessionContext(s, ctx)
+			}
+			if userId, ok := ctx.Value(utils.UserIdKey).(string); ok {
+				ctx = rolesContext(userService, userId, ctx)
 			}
+
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
 // authentication middleware for secured REST endpoints
-func RestAuthMiddleware(next http.Handler, firebaseClient *client.FirebaseClient) http.Handler {
+func RestAuthMiddleware(next http.Handler, identityProvider identity.IdentityProvider, userService *user.UserService) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
@@ -82,60 +87,130 @@ func RestAuthMiddleware(next http.Handler, firebaseClient *client.FirebaseClient
 		}
 
 		idToken := parts[1]
-		if firebaseClient == nil {
+		if identityProvider == nil {
 			return
 		}
 
-		token, err := firebaseClient.VerifyIDToken(idToken)
-		if err != nil || !token.Valid {
+		claims, err := identityProvider.VerifyToken(r.Context(), idToken)
+		if err != nil {
 			http.Error(w, "Forbidden", http.StatusForbidden)
 			return
 		}
-		ctx := updateContext(token, r.Context())
+		ctx := updateContext(claims, r.Context())
+		ctx = rolesContext(userService, claims.UserId, ctx)
 
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
-func updateContext(token *jwt.Token, ctx context.Context) context.Context {
-	claims := token.Claims.(jwt.MapClaims)
+// Restricts the handler to users holding any of the roles, must be wrapped
+// by an authentication middleware. Admins are not implied, list them explicitly.
+func RequireRole(next http.Handler, roles ...user.Role) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if utils.HasRole(r.Context(), roles...) {
+			next.ServeHTTP(w, r)
+			return
+		}
 
-	userId := claims["user_id"]
-	email := claims[string(utils.EmailKey)].(string)
+		if strings.HasPrefix(r.URL.Path, "/api/v1") {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		if !utils.Logined(r.Context()) {
+			if r.Header.Get("HX-Request") == "true" {
+				w.Header().Set("HX-Redirect", "/login")
+				return
+			}
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
 
-	ctx = context.WithValue(ctx, utils.UserIdKey, userId)
-	ctx = context.WithValue(ctx, utils.EmailKey, email)
-	ctx = context.WithValue(ctx, utils.NameKey, email)
+		w.WriteHeader(http.StatusForbidden)
+		templates.Layout(templates.ErrorMessage("You are not allowed to access this page")).Render(r.Context(), w)
+	})
+}
+
+func sessionContext(s *session.Session, ctx context.Context) context.Context {
+	ctx = context.WithValue(ctx, utils.UserIdKey, s.UserId)
+	ctx = context.WithValue(ctx, utils.EmailKey, s.Email)
+	ctx = context.WithValue(ctx, utils.NameKey, s.Email)
 	ctx = context.WithValue(ctx, utils.LoginedKey, true)
+	ctx = context.WithValue(ctx, utils.EmailVerifiedKey, s.EmailVerified)
+	ctx = context.WithValue(ctx, utils.SessionIdKey, s.ID)
 
 	return ctx
 }
 
-func refreshToken(firebaseClient *client.FirebaseClient, w http.ResponseWriter, r *http.Request, logger *slog.Logger) {
-	// TODO: update refresh token
-	if refreshTokenCookie, err := r.Cookie(string(utils.RefreshTokenKey)); err == nil {
-		refreshToken := refreshTokenCookie.Value
-		token, err := firebaseClient.VerifyIDToken(refreshToken)
-		if err != nil {
-			logger.Error("not able to refresh token", slog.Any("err", err))
+func updateContext(claims *identity.Claims, ctx context.Context) context.Context {
+	ctx = context.WithValue(ctx, utils.UserIdKey, claims.UserId)
+	ctx = context.WithValue(ctx, utils.EmailKey, claims.Email)
+	ctx = context.WithValue(ctx, utils.NameKey, claims.Email)
+	ctx = context.WithValue(ctx, utils.LoginedKey, true)
+	ctx = context.WithValue(ctx, utils.EmailVerifiedKey, claims.EmailVerified)
 
-			utils.ClearTokenCookies(w)
+	return ctx
+}
 
-			w.Header().Set("HX-Redirect", "/")
-			w.WriteHeader(http.StatusOK)
-			return
+// Roles are only queried when a handler or template checks them, at most once per request
+func rolesContext(userService *user.UserService, userId string, ctx context.Context) context.Context {
+	loadRoles := sync.OnceValue(func() []string {
+		roles := userService.GetRoles(userId)
+		names := make([]string, len(roles))
+		for i, role := range roles {
+			names[i] = string(role)
 		}
+		return names
+	})
+	return context.WithValue(ctx, utils.RolesKey, loadRoles)
+}
 
-		claims := token.Claims.(jwt.MapClaims)
-		tokenJSON, _ := json.Marshal(token)
-		http.SetCookie(w, &http.Cookie{
-			Name:     string(utils.IdTokenKey),
-			Value:    string(tokenJSON),
-			Path:     "/",
-			Expires:  time.Unix(claims["exp"].(int64), 0),
-			HttpOnly: true,
-			Secure:   true,
-			SameSite: http.SameSiteStrictMode,
-		})
+// Users logined before sessions were introduced still hold ID and refresh
+// token cookies, which are exchanged for a session
+func migrateTokenCookies(sessionService *session.SessionService, identityProvider identity.IdentityProvider, w http.ResponseWriter, r *http.Request, logger *slog.Logger) *session.Session {
+	idTokenCookie, _ := r.Cookie(string(utils.IdTokenKey))
+	refreshTokenCookie, _ := r.Cookie(string(utils.RefreshTokenKey))
+	if idTokenCookie == nil && refreshTokenCookie == nil {
+		return nil
+	}
+	utils.ClearTokenCookies(w)
+
+	var claims *identity.Claims
+	if idTokenCookie != nil {
+		claims, _ = identityProvider.VerifyToken(r.Context(), idTokenCookie.Value)
+	}
+	if claims == nil && refreshTokenCookie != nil {
+		claims = refreshToken(identityProvider, refreshTokenCookie.Value, r.Context(), logger)
+	}
+	if claims == nil {
+		return nil
+	}
+
+	token, s, err := sessionService.CreateSession(claims.UserId, r.UserAgent(), utils.ClientIp(r))
+	if err != nil {
+		logger.Error("not able to create session", slog.Any("err", err))
+		return nil
+	}
+	s.Email = claims.Email
+	s.EmailVerified = claims.EmailVerified
+
+	utils.SetSessionCookie(w, token, s.ExpiresOn)
+	return s
+}
+
+// Exchanges the refresh token for a new ID token, returns nil when the
+// refresh token is no longer valid
+func refreshToken(identityProvider identity.IdentityProvider, refreshToken string, ctx context.Context, logger *slog.Logger) *identity.Claims {
+	tokens, err := identityProvider.Refresh(ctx, refreshToken)
+	if err != nil {
+		logger.Error("not able to refresh token", slog.Any("err", err))
+		return nil
+	}
+
+	claims, err := identityProvider.VerifyToken(ctx, tokens.IdToken)
+	if err != nil {
+		logger.Error("not able to verify refreshed token", slog.Any("err", err))
+		return nil
 	}
+	return claims
 }
@@ -0,0 +1,130 @@
+package city
+
+import (
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/sportspazz/api/client"
+	"github.com/sportspazz/utils"
+)
+
+var ErrCityNotFound = errors.New("city not found")
+
+const (
+	// place ids Google could not resolve are not looked up again for a while
+	missingCityTTL   = time.Hour
+	maxMissingCities = 10000
+)
+
+type CityService struct {
+	store      *CityStore
+	mapsClient *client.GoogleMapsClient
+	logger     *slog.Logger
+
+	mu sync.Mutex
+	// expiry of the negative lookups by place id
+	missing map[string]time.Time
+}
+
+func NewCityService(store *CityStore, mapsClient *client.GoogleMapsClient, logger *slog.Logger) *CityService {
+	return &CityService{
+		store:      store,
+		mapsClient: mapsClient,
+		logger:     logger,
+		missing:    make(map[string]time.Time),
+	}
+}
+
+// Returns the city of the Google place id, looking it up from Google and
+// storing it the first time the city is used.
+func (c *CityService) EnsureCity(placeId string) (*City, error) {
+	if city := c.store.GetCityByPlaceId(placeId); city != nil {
+		return city, nil
+	}
+	if c.knownMissing(placeId) {
+		return nil, ErrCityNotFound
+	}
+
+	place, err := c.mapsClient.GetPlace(placeId)
+	if err != nil {
+		c.logger.Error("not able to look up city", slog.String("placeId", placeId), slog.Any("err", err))
+		c.setMissing(placeId)
+		return nil, ErrCityNotFound
+	}
+
+	latitude, longitude := place.Geometry.Location.Lat, place.Geometry.Location.Lng
+	timeZone, err := c.mapsClient.GetTimeZone(latitude, longitude)
+	if err != nil {
+		c.logger.Warn("not able to look up city time zone", slog.String("placeId", placeId), slog.Any("err", err))
+	}
+
+	region := place.AddressComponent("administrative_area_level_1")
+	country := place.AddressComponent("country")
+
+	return c.store.CreateCity(placeId, c.uniqueSlug(placeId, place.Name, region, country), place.Name, region, country, latitude, longitude, timeZone)
+}
+
+func (c *CityService) knownMissing(placeId string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiry, ok := c.missing[placeId]
+	if ok && time.Now().After(expiry) {
+		delete(c.missing, placeId)
+		return false
+	}
+	return ok
+}
+
+func (c *CityService) setMissing(placeId string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if len(c.missing) >= maxMissingCities {
+		for id, expiry := range c.missing {
+			if now.After(expiry) {
+				delete(c.missing, id)
+			}
+		}
+		// still full of recent failures, forget them rather than grow
+		if len(c.missing) >= maxMissingCities {
+			clear(c.missing)
+		}
+	}
+	c.missing[placeId] = now.Add(missingCityTTL)
+}
+
+func (c *CityService) GetCityBySlug(slug string) *City {
+	return c.store.GetCityBySlug(slug)
+}
+
+func (c *CityService) GetCityByPlaceId(placeId string) *City {
+	return c.store.GetCityByPlaceId(placeId)
+}
+
+func (c *CityService) SearchCities(namePrefix string, limit int) []City {
+	return c.store.SearchCities(namePrefix, limit)
+}
+
+// e.g. "london", then "london-ontario" and "london-ontario-canada" for cities sharing a name
+func (c *CityService) uniqueSlug(placeId, name, region, country string) string {
+	slug := utils.Slugify(name)
+	if slug == "" {
+		return utils.Slugify(placeId)
+	}
+	for _, qualifier := range []string{region, country} {
+		if !c.store.SlugExists(slug) {
+			return slug
+		}
+		if qualifier != "" {
+			slug += "-" + utils.Slugify(qualifier)
+		}
+	}
+	if c.store.SlugExists(slug) {
+		slug += "-" + utils.Slugify(placeId)
+	}
+	return slug
+}
@@ -0,0 +1,95 @@
+package city
+
+import (
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sportspazz/utils"
+	"gorm.io/gorm"
+)
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+type CityStore struct {
+	db     *gorm.DB
+	logger *slog.Logger
+}
+
+func NewCityStore(db *gorm.DB, logger *slog.Logger) *CityStore {
+	return &CityStore{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (s *CityStore) CreateCity(placeId, slug, name, region, country string, latitude, longitude float64, timeZone string) (*City, error) {
+	city := &City{
+		ID:        uuid.New().String(),
+		CreatedOn: time.Now().UTC(),
+		PlaceId:   placeId,
+		Slug:      slug,
+		Name:      name,
+		Region:    region,
+		Country:   country,
+		Latitude:  latitude,
+		Longitude: longitude,
+		TimeZone:  timeZone,
+	}
+
+	if err := s.db.Create(city).Error; err != nil {
+		s.logger.Error("not able to create a new city", slog.Any("err", err))
+		return nil, err
+	}
+
+	return city, nil
+}
+
+func (s *CityStore) GetCityByPlaceId(placeId string) *City {
+	var city City
+	if err := s.db.First(&city, "place_id = ?", placeId).Error; err != nil {
+		return nil
+	}
+	return &city
+}
+
+func (s *CityStore) GetCityBySlug(slug string) *City {
+	var city City
+	if err := s.db.First(&city, "slug = ?", slug).Error; err != nil {
+		return nil
+	}
+	return &city
+}
+
+func (s *CityStore) SlugExists(slug string) bool {
+	var count int64
+	s.db.Model(&City{}).Where("slug = ?", slug).Count(&count)
+	return count > 0
+}
+
+// Cities with at least one place, matching the name prefix when given
+func (s *CityStore) SearchCities(namePrefix string, limit int) []City {
+	query := s.db.Model(&City{}).
+		Select(`cities.*, (SELECT COUNT(*) FROM pois
+			WHERE pois.city_id = cities.place_id AND pois.deleted_on IS NULL) AS poi_count`)
+	if namePrefix != "" {
+		query = query.Where("LOWER(name) LIKE ? OR slug LIKE ?",
+			escapeLike(strings.ToLower(namePrefix))+"%", escapeLike(utils.Slugify(namePrefix))+"%")
+	}
+
+	var cities []City
+	if err := s.db.Table("(?) AS cities", query).
+		Where("poi_count > 0").
+		Order("poi_count DESC, name").
+		Limit(limit).
+		Find(&cities).Error; err != nil {
+		s.logger.Error("not able to search cities", slog.Any("err", err))
+	}
+
+	return cities
+}
+
+func escapeLike(value string) string {
+	return likeEscaper.Replace(value)
+}
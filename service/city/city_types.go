@@ -0,0 +1,27 @@
+package city
+
+import "time"
+
+type City struct {
+	internalId uint `gorm:"primaryKey"`
+	ID         string
+	CreatedOn  time.Time `gorm:"type:timestamp(3) without time zone" json:"created_on"`
+	PlaceId    string
+	Slug       string
+	Name       string
+	Region     string
+	Country    string
+	Latitude   float64
+	Longitude  float64
+	TimeZone   string
+	// number of places in the city, only populated by searches
+	PoiCount int64 `gorm:"->"`
+}
+
+// Display name, e.g. "Toronto, Ontario"
+func (c City) FullName() string {
+	if c.Region == "" {
+		return c.Name
+	}
+	return c.Name + ", " + c.Region
+}
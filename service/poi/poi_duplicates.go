@@ -0,0 +1,91 @@
+package poi
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+const (
+	// candidates scoring at least this are reported as likely duplicates
+	duplicateThreshold = 0.6
+	// places further apart than this get no distance score
+	duplicateRadiusM = 500.0
+	maxDuplicates    = 5
+)
+
+// Words too common in place names to tell places apart
+var duplicateStopWords = map[string]bool{
+	"the": true, "and": true, "of": true, "at": true, "a": true, "&": true,
+}
+
+type DuplicateCandidate struct {
+	Poi   Poi
+	Score float64
+}
+
+// Scores how likely the candidate is the same place, from 0 to 1, weighing the
+// name, the address and the distance when both places have coordinates
+func duplicateScore(name, address string, latitude, longitude *float64, candidate Poi) float64 {
+	nameScore := similarity(name, candidate.Name)
+	addressScore := similarity(address, candidate.Address)
+
+	if latitude == nil || longitude == nil || candidate.Latitude == nil || candidate.Longitude == nil {
+		return 0.7*nameScore + 0.3*addressScore
+	}
+
+	distanceM := distanceKm(*latitude, *longitude, *candidate.Latitude, *candidate.Longitude) * 1000
+	distanceScore := math.Max(0, 1-distanceM/duplicateRadiusM)
+	return 0.5*nameScore + 0.2*addressScore + 0.3*distanceScore
+}
+
+// Jaccard similarity of the trigrams of the normalized strings, like pg_trgm
+func similarity(a, b string) float64 {
+	trigramsA, trigramsB := trigrams(normalize(a)), trigrams(normalize(b))
+	if len(trigramsA) == 0 || len(trigramsB) == 0 {
+		return 0
+	}
+
+	shared := 0
+	for trigram := range trigramsA {
+		if trigramsB[trigram] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(trigramsA)+len(trigramsB)-shared)
+}
+
+// Lower cases the words and drops punctuation and stop words
+func normalize(value string) string {
+	words := strings.FieldsFunc(strings.ToLower(value), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	kept := words[:0]
+	for _, word := range words {
+		if !duplicateStopWords[word] {
+			kept = append(kept, word)
+		}
+	}
+	return strings.Join(kept, " ")
+}
+
+// Words are padded so their start and end count as trigrams too
+func trigrams(value string) map[string]bool {
+	set := make(map[string]bool)
+	for _, word := range strings.Fields(value) {
+		padded := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			set[string(padded[i:i+3])] = true
+		}
+	}
+	return set
+}
+
+func distanceKm(lat1, lng1, lat2, lng2 float64) float64 {
+	dLat := (lat2 - lat1) * math.Pi / 180
+	dLng := (lng2 - lng1) * math.Pi / 180
+	h := math.Pow(math.Sin(dLat/2), 2) +
+		math.Cos(lat1*math.Pi/180)*math.Cos(lat2*math.Pi/180)*math.Pow(math.Sin(dLng/2), 2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h))
+}
@@ -1,23 +1,112 @@
 package poi
 
 import (
+	"errors"
 	"log/slog"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sportspazz/imaging"
+	"github.com/sportspazz/service/city"
+	"github.com/sportspazz/service/sport"
+)
+
+const (
+	maxMapPois     = 200
+	mapClusterGrid = 8
+	// places are hidden once this many users have open reports on them
+	reportHideThreshold   = 3
+	maxReportsPerHour     = 10
+	maxSuggestionsPerHour = 10
+	maxReviewedReports    = 500
+)
+
+var (
+	ErrPoiNotFound          = errors.New("poi not found")
+	ErrPoiAlreadyExist      = errors.New("poi already exists")
+	ErrPoiNotPending        = errors.New("poi is not pending moderation")
+	ErrNoRejectReason       = errors.New("rejection reason is required")
+	ErrRevisionNotFound     = errors.New("poi revision not found")
+	ErrEmptySuggestion      = errors.New("suggestion does not change anything")
+	ErrSuggestionNotFound   = errors.New("edit suggestion not found")
+	ErrSuggestionNotPending = errors.New("edit suggestion was already reviewed")
+	ErrSuggestRateLimited   = errors.New("too many suggestions, try again later")
+	ErrUnknownReportReason  = errors.New("unknown report reason")
+	ErrAlreadyReported      = errors.New("poi already reported")
+	ErrReportRateLimited    = errors.New("too many reports, try again later")
+	ErrInvalidDuplicate     = errors.New("duplicate_of must be the id of another poi")
+	ErrMergeIntoSelf        = errors.New("poi cannot be merged into itself")
+	ErrPhotoNotFound        = errors.New("photo not found")
+	ErrPhotoNotPending      = errors.New("photo was already moderated")
+	ErrPhotoNotApproved     = errors.New("only approved photos can be the cover")
+	ErrCaptionTooLong       = errors.New("caption must be less than 200 characters")
+	ErrNotPlaceOwner        = errors.New("only the creator of the place or a moderator can change the cover")
+	ErrReviewNotFound       = errors.New("review not found")
+	ErrAlreadyReviewed      = errors.New("poi already reviewed, edit the review instead")
+	ErrNotReviewAuthor      = errors.New("only the author can change the review")
+	ErrInvalidStars         = errors.New("stars must be 1 to 5")
+	ErrReviewTooLong        = errors.New("review must be less than 4000 characters")
+	ErrVisitInFuture        = errors.New("visit date cannot be in the future")
 )
 
 type PoiService struct {
-	store  *PoiStore
-	logger *slog.Logger
+	store        *PoiStore
+	sportService *sport.SportService
+	cityService  *city.CityService
+	logger       *slog.Logger
 }
 
-func NewPoiService(store *PoiStore, logger *slog.Logger) *PoiService {
+func NewPoiService(store *PoiStore, sportService *sport.SportService, cityService *city.CityService, logger *slog.Logger) *PoiService {
 	return &PoiService{
-		store:  store,
-		logger: logger,
+		store:        store,
+		sportService: sportService,
+		cityService:  cityService,
+		logger:       logger,
 	}
 }
 
-func (p *PoiService) CreatePoi(createdBy, name, description, address, cityId string, googlePlaceId *string, website, sportType, thumbnailUrl, note string) (Poi, error) {
-	return p.store.CreatePoi(createdBy, name, description, address, cityId, googlePlaceId, website, sportType, thumbnailUrl, note), nil
+// The first sport type is the primary sport of the place. Places of
+// non-trusted users are pending until a moderator approves them. A given
+// thumbnailImport is queued with the place and fetched in the background.
+func (p *PoiService) CreatePoi(createdBy, name, description, address, cityId string, googlePlaceId *string, latitude, longitude *float64, website string, sportTypes []string, thumbnail Renditions, thumbnailImport *ThumbnailImport, note string, trusted bool) (Poi, error) {
+	sports, err := p.sportService.ResolveSports(sportTypes)
+	if err != nil {
+		return Poi{}, err
+	}
+
+	// the city is looked up from Google the first time a place is added to it
+	if _, err := p.cityService.EnsureCity(cityId); err != nil {
+		p.logger.Warn("poi created in unknown city", slog.String("cityId", cityId), slog.Any("err", err))
+	}
+
+	status := PoiPending
+	if trusted {
+		status = PoiApproved
+	}
+
+	return p.store.CreatePoi(createdBy, name, description, address, cityId, googlePlaceId, latitude, longitude, website, sports, thumbnail, thumbnailImport, note, status)
+}
+
+func (p *PoiService) AddPoiSports(id, updatedBy string, sportTypes []string) (*Poi, error) {
+	if p.store.GetPoiById(id) == nil {
+		return nil, ErrPoiNotFound
+	}
+
+	sports, err := p.sportService.ResolveSports(sportTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.store.AddPoiSports(id, updatedBy, sports); err != nil {
+		p.logger.Error("not able to add sports to poi", slog.String("id", id), slog.Any("err", err))
+		return nil, err
+	}
+
+	return p.store.GetPoiById(id), nil
 }
 
 func (p *PoiService) GetPoiByGooglePlaceId(googlePlaceId string) *Poi {
@@ -28,11 +117,653 @@ func (p *PoiService) GetPoiById(id string) *Poi {
 	return p.store.GetPoiById(id)
 }
 
+func (p *PoiService) UpdatePoi(id, updatedBy string, update PoiUpdate) (*Poi, error) {
+	existing := p.store.GetPoiById(id)
+	if existing == nil {
+		return nil, ErrPoiNotFound
+	}
+	// an unchanged thumbnail keeps its renditions and attributions
+	if update.ThumbnailUrl != nil && *update.ThumbnailUrl == existing.ThumbnailUrl {
+		update.ThumbnailUrl = nil
+	}
+	if update.ThumbnailImport != nil {
+		update.ThumbnailImport.ReplacesThumbnailUrl = &existing.ThumbnailUrl
+	}
+
+	var sports []sport.Sport
+	if update.SportTypes != nil {
+		resolved, err := p.sportService.ResolveSports(update.SportTypes)
+		if err != nil {
+			return nil, err
+		}
+		sports = resolved
+	}
+
+	if err := p.store.UpdatePoi(id, updatedBy, update, sports); err != nil {
+		p.logger.Error("not able to update poi", slog.String("id", id), slog.Any("err", err))
+		return nil, err
+	}
+
+	return p.store.GetPoiById(id), nil
+}
+
+func (p *PoiService) DeletePoi(id, deletedBy string) error {
+	if p.store.GetPoiById(id) == nil {
+		return ErrPoiNotFound
+	}
+
+	if err := p.store.DeletePoi(id, deletedBy); err != nil {
+		p.logger.Error("not able to delete poi", slog.String("id", id), slog.Any("err", err))
+		return err
+	}
+
+	return nil
+}
+
+func (p *PoiService) RestorePoi(id, restoredBy string) (*Poi, error) {
+	deleted := p.store.GetDeletedPoiById(id)
+	if deleted == nil {
+		return nil, ErrPoiNotFound
+	}
+	if deleted.GooglePlaceId != nil && p.store.GetPoiByGooglePlaceId(*deleted.GooglePlaceId) != nil {
+		return nil, ErrPoiAlreadyExist
+	}
+
+	if err := p.store.RestorePoi(id, restoredBy); err != nil {
+		p.logger.Error("not able to restore poi", slog.String("id", id), slog.Any("err", err))
+		return nil, err
+	}
+
+	return p.store.GetPoiById(id), nil
+}
+
+// Newest first, each revision with the changes made to the previous one
+func (p *PoiService) GetPoiRevisions(id string) []PoiRevision {
+	revisions := p.store.GetPoiRevisions(id)
+	for i := len(revisions) - 1; i > 0; i-- {
+		revisions[i].Changes = revisions[i].Snapshot.Diff(revisions[i-1].Snapshot)
+	}
+	slices.Reverse(revisions)
+	return revisions
+}
+
+// Puts back the content the place had at the revision. Deleted places must be
+// restored first, moderation status is not affected.
+func (p *PoiService) RevertPoi(id, revisionId, revertedBy string) (*Poi, error) {
+	existing := p.store.GetPoiById(id)
+	if existing == nil {
+		return nil, ErrPoiNotFound
+	}
+	revision := p.store.GetPoiRevision(id, revisionId)
+	if revision == nil {
+		return nil, ErrRevisionNotFound
+	}
+
+	snapshot := revision.Snapshot
+	// the primary sport goes first
+	sportTypes := append([]string{snapshot.SportType}, snapshot.Sports...)
+	sports, err := p.sportService.ResolveSports(sportTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	update := PoiUpdate{
+		Name:        &snapshot.Name,
+		Address:     &snapshot.Address,
+		Website:     &snapshot.Website,
+		CityId:      &snapshot.CityId,
+		Latitude:    snapshot.Latitude,
+		Longitude:   snapshot.Longitude,
+		Description: &snapshot.Description,
+		Note:        &snapshot.Note,
+	}
+	// revisions do not keep the renditions, an unchanged thumbnail keeps its own
+	if snapshot.ThumbnailUrl != existing.ThumbnailUrl {
+		update.ThumbnailUrl = &snapshot.ThumbnailUrl
+		update.ThumbnailAttributions = snapshot.ThumbnailAttributions
+	}
+	if err := p.store.RevertPoi(id, revertedBy, update, sports); err != nil {
+		p.logger.Error("not able to revert poi", slog.String("id", id), slog.String("revisionId", revisionId), slog.Any("err", err))
+		return nil, err
+	}
+
+	p.logger.Info("poi reverted", slog.String("id", id), slog.String("revisionId", revisionId), slog.String("revertedBy", revertedBy))
+	return p.store.GetPoiById(id), nil
+}
+
+// Fields equal to the current values of the place are dropped from the suggestion
+func (p *PoiService) SuggestEdit(poiId, suggestedBy string, changes SuggestedChanges, comment string) (*PoiEditSuggestion, error) {
+	existing := p.store.GetPoiById(poiId)
+	if existing == nil {
+		return nil, ErrPoiNotFound
+	}
+	if p.store.CountSuggestionsSince(suggestedBy, time.Now().UTC().Add(-time.Hour)) >= maxSuggestionsPerHour {
+		return nil, ErrSuggestRateLimited
+	}
+
+	changes.Name = changedValue(changes.Name, existing.Name)
+	changes.Address = changedValue(changes.Address, existing.Address)
+	changes.Website = changedValue(changes.Website, existing.Website)
+	changes.Description = changedValue(changes.Description, existing.Description)
+	if changes.IsEmpty() {
+		return nil, ErrEmptySuggestion
+	}
+
+	suggestion := &PoiEditSuggestion{
+		ID:        uuid.New().String(),
+		PoiId:     poiId,
+		CreatedOn: time.Now().UTC(),
+		CreatedBy: suggestedBy,
+		Status:    SuggestionPending,
+		Changes:   changes,
+		Comment:   strings.TrimSpace(comment),
+	}
+	if err := p.store.CreateSuggestion(suggestion); err != nil {
+		return nil, err
+	}
+	return suggestion, nil
+}
+
+func changedValue(value *string, current string) *string {
+	if value == nil {
+		return nil
+	}
+	trimmed := strings.TrimSpace(*value)
+	if trimmed == current {
+		return nil
+	}
+	return &trimmed
+}
+
+// Each suggestion comes with its place as it is now
+func (p *PoiService) GetPendingSuggestions(limit int) []PoiEditSuggestion {
+	suggestions := p.store.GetPendingSuggestions(limit)
+	for i := range suggestions {
+		suggestions[i].Poi = p.store.GetPoiById(suggestions[i].PoiId)
+	}
+	return suggestions
+}
+
+// The place is populated, so the suggestion can be shown for review again
+func (p *PoiService) GetSuggestionById(id string) *PoiEditSuggestion {
+	suggestion := p.store.GetSuggestionById(id)
+	if suggestion != nil {
+		suggestion.Poi = p.store.GetPoiById(suggestion.PoiId)
+	}
+	return suggestion
+}
+
+func (p *PoiService) GetSuggestionCounts(userId string) SuggestionCounts {
+	return p.store.GetSuggestionCounts(userId)
+}
+
+// Applies the suggested changes as an edit of the reviewer
+func (p *PoiService) AcceptSuggestion(id, reviewedBy string) (*PoiEditSuggestion, error) {
+	suggestion := p.store.GetSuggestionById(id)
+	if suggestion == nil {
+		return nil, ErrSuggestionNotFound
+	}
+	if suggestion.Status != SuggestionPending {
+		return nil, ErrSuggestionNotPending
+	}
+
+	if p.store.GetPoiById(suggestion.PoiId) == nil {
+		return nil, ErrPoiNotFound
+	}
+
+	accepted, err := p.store.AcceptSuggestion(suggestion, reviewedBy)
+	if err != nil {
+		p.logger.Error("not able to accept edit suggestion", slog.String("id", id), slog.Any("err", err))
+		return nil, err
+	}
+	if !accepted {
+		return nil, ErrSuggestionNotPending
+	}
+
+	p.logger.Info("edit suggestion reviewed", slog.String("id", id), slog.String("status", string(SuggestionAccepted)), slog.String("reviewedBy", reviewedBy))
+	return p.store.GetSuggestionById(id), nil
+}
+
+func (p *PoiService) RejectSuggestion(id, reviewedBy, note string) (*PoiEditSuggestion, error) {
+	suggestion := p.store.GetSuggestionById(id)
+	if suggestion == nil {
+		return nil, ErrSuggestionNotFound
+	}
+
+	var reviewNote *string
+	if note = strings.TrimSpace(note); note != "" {
+		reviewNote = &note
+	}
+	return p.reviewSuggestion(suggestion, reviewedBy, SuggestionRejected, reviewNote)
+}
+
+func (p *PoiService) reviewSuggestion(suggestion *PoiEditSuggestion, reviewedBy string, status SuggestionStatus, reviewNote *string) (*PoiEditSuggestion, error) {
+	reviewed, err := p.store.ReviewSuggestion(suggestion.ID, reviewedBy, status, reviewNote)
+	if err != nil {
+		p.logger.Error("not able to review edit suggestion", slog.String("id", suggestion.ID), slog.Any("err", err))
+		return nil, err
+	}
+	if !reviewed {
+		return nil, ErrSuggestionNotPending
+	}
+
+	p.logger.Info("edit suggestion reviewed", slog.String("id", suggestion.ID), slog.String("status", string(status)), slog.String("reviewedBy", reviewedBy))
+	return p.store.GetSuggestionById(suggestion.ID), nil
+}
+
+// Users have one open report per place, and the place is hidden once enough
+// users reported it
+func (p *PoiService) ReportPoi(poiId, reportedBy string, reason ReportReason, duplicateOf, comment string) (*PoiReport, error) {
+	existing := p.store.GetPoiById(poiId)
+	if existing == nil {
+		return nil, ErrPoiNotFound
+	}
+	if p.store.CountReportsSince(reportedBy, time.Now().UTC().Add(-time.Hour)) >= maxReportsPerHour {
+		return nil, ErrReportRateLimited
+	}
+	if p.store.HasOpenReport(poiId, reportedBy) {
+		return nil, ErrAlreadyReported
+	}
+
+	report := &PoiReport{
+		ID:        uuid.New().String(),
+		PoiId:     poiId,
+		CreatedOn: time.Now().UTC(),
+		CreatedBy: reportedBy,
+		Reason:    reason,
+		Comment:   strings.TrimSpace(comment),
+	}
+	if reason == ReportDuplicate {
+		if duplicateOf == poiId || p.store.GetPoiById(duplicateOf) == nil {
+			return nil, ErrInvalidDuplicate
+		}
+		report.DuplicateOf = &duplicateOf
+	}
+	if err := p.store.CreateReport(report); err != nil {
+		return nil, err
+	}
+
+	if existing.HiddenOn == nil && p.store.CountOpenReports(poiId) >= reportHideThreshold {
+		if _, err := p.store.SetPoiHidden(poiId, SystemUserId, true); err != nil {
+			p.logger.Error("not able to hide reported poi", slog.String("id", poiId), slog.Any("err", err))
+		} else {
+			p.logger.Info("poi hidden after reports", slog.String("id", poiId))
+		}
+	}
+
+	return report, nil
+}
+
+// Places with open reports, the most reported first
+func (p *PoiService) GetReportedPois() []ReportedPoi {
+	var reported []ReportedPoi
+	byPoi := make(map[string]int)
+	for _, report := range p.store.GetOpenReports(maxReviewedReports) {
+		i, ok := byPoi[report.PoiId]
+		if !ok {
+			existing := p.store.GetPoiById(report.PoiId)
+			if existing == nil {
+				continue
+			}
+			i = len(reported)
+			byPoi[report.PoiId] = i
+			reported = append(reported, ReportedPoi{
+				Poi:          *existing,
+				Reasons:      make(map[ReportReason]int),
+				LastReportOn: report.CreatedOn,
+			})
+		}
+
+		r := &reported[i]
+		r.Reporters++
+		r.Reasons[report.Reason]++
+		if report.DuplicateOf != nil && !slices.ContainsFunc(r.DuplicateOf, func(duplicate Poi) bool { return duplicate.ID == *report.DuplicateOf }) {
+			// places deleted or merged since the report are left out
+			if duplicate := p.store.GetPoiById(*report.DuplicateOf); duplicate != nil {
+				r.DuplicateOf = append(r.DuplicateOf, *duplicate)
+			}
+		}
+		if report.Comment != "" {
+			r.Comments = append(r.Comments, report.Comment)
+		}
+	}
+
+	slices.SortStableFunc(reported, func(a, b ReportedPoi) int {
+		return b.Reporters - a.Reporters
+	})
+	return reported
+}
+
+// The reports were unfounded, the place is shown again if it was hidden
+func (p *PoiService) DismissReports(poiId, resolvedBy string) error {
+	if p.store.GetPoiById(poiId) == nil {
+		return ErrPoiNotFound
+	}
+
+	if err := p.store.ResolveReports(poiId, resolvedBy, ReportDismissed); err != nil {
+		p.logger.Error("not able to dismiss poi reports", slog.String("id", poiId), slog.Any("err", err))
+		return err
+	}
+	if _, err := p.store.SetPoiHidden(poiId, resolvedBy, false); err != nil {
+		p.logger.Error("not able to unhide poi", slog.String("id", poiId), slog.Any("err", err))
+		return err
+	}
+	return nil
+}
+
+// The reports were right, the place is deleted
+func (p *PoiService) DeleteReportedPoi(poiId, resolvedBy string) error {
+	if err := p.DeletePoi(poiId, resolvedBy); err != nil {
+		return err
+	}
+
+	if err := p.store.ResolveReports(poiId, resolvedBy, ReportActioned); err != nil {
+		p.logger.Error("not able to resolve poi reports", slog.String("id", poiId), slog.Any("err", err))
+		return err
+	}
+	return nil
+}
+
+// Photos of trusted users are shown right away, the others once a moderator approves them
+func (p *PoiService) AddPhoto(poiId, uploadedBy string, renditions Renditions, caption string, trusted bool) (*PoiPhoto, error) {
+	caption = strings.TrimSpace(caption)
+	if len(caption) > maxPhotoCaptionLength {
+		return nil, ErrCaptionTooLong
+	}
+	if p.store.GetPoiById(poiId) == nil {
+		return nil, ErrPoiNotFound
+	}
+
+	status := PhotoPending
+	if trusted {
+		status = PhotoApproved
+	}
+	photo := PoiPhoto{
+		ID:         uuid.New().String(),
+		PoiId:      poiId,
+		CreatedOn:  time.Now().UTC(),
+		CreatedBy:  uploadedBy,
+		Renditions: renditions,
+		Url:        imaging.JpegUrl(renditions, imaging.FallbackWidth),
+		Caption:    caption,
+		Status:     status,
+	}
+	if err := p.store.CreatePhoto(&photo); err != nil {
+		p.logger.Error("not able to create poi photo", slog.String("poiId", poiId), slog.Any("err", err))
+		return nil, err
+	}
+
+	return &photo, nil
+}
+
+func (p *PoiService) GetPhotos(poiId, viewerId string) []PoiPhoto {
+	return p.store.GetPhotos(poiId, viewerId, maxPhotosListed)
+}
+
+func (p *PoiService) GetPendingPhotos(limit int) []PoiPhoto {
+	photos := p.store.GetPendingPhotos(limit)
+	for i := range photos {
+		photos[i].Poi = p.store.GetPoiById(photos[i].PoiId)
+	}
+	return photos
+}
+
+func (p *PoiService) ApprovePhoto(id, moderatedBy string) (*PoiPhoto, error) {
+	return p.moderatePhoto(id, moderatedBy, PhotoApproved)
+}
+
+// The caller removes the rejected photo from the blob store
+func (p *PoiService) RejectPhoto(id, moderatedBy string) (*PoiPhoto, error) {
+	return p.moderatePhoto(id, moderatedBy, PhotoRejected)
+}
+
+func (p *PoiService) moderatePhoto(id, moderatedBy string, status PhotoStatus) (*PoiPhoto, error) {
+	if p.store.GetPhotoById(id) == nil {
+		return nil, ErrPhotoNotFound
+	}
+
+	moderated, err := p.store.ModeratePhoto(id, moderatedBy, status)
+	if err != nil {
+		p.logger.Error("not able to moderate poi photo", slog.String("id", id), slog.Any("err", err))
+		return nil, err
+	}
+	if !moderated {
+		return nil, ErrPhotoNotPending
+	}
+
+	return p.store.GetPhotoById(id), nil
+}
+
+// The creator of the place and moderators pick which photo is the thumbnail
+func (p *PoiService) SetCoverPhoto(poiId, photoId, userId string, moderator bool) (*Poi, error) {
+	existing := p.store.GetPoiById(poiId)
+	if existing == nil {
+		return nil, ErrPoiNotFound
+	}
+	if existing.CreatedBy != userId && !moderator {
+		return nil, ErrNotPlaceOwner
+	}
+	photo := p.store.GetPhotoById(photoId)
+	if photo == nil || photo.PoiId != poiId {
+		return nil, ErrPhotoNotFound
+	}
+	if photo.Status != PhotoApproved {
+		return nil, ErrPhotoNotApproved
+	}
+
+	if err := p.store.SetCoverPhoto(*photo, userId); err != nil {
+		p.logger.Error("not able to set cover photo", slog.String("poiId", poiId), slog.Any("err", err))
+		return nil, err
+	}
+
+	return p.store.GetPoiById(poiId), nil
+}
+
+func (p *PoiService) GetReviews(poiId string) []Review {
+	return p.store.GetReviews(poiId, maxReviewsListed)
+}
+
+func (p *PoiService) GetUserReview(poiId, userId string) *Review {
+	return p.store.GetUserReview(poiId, userId)
+}
+
+// Users review a place once, later changes edit that review
+func (p *PoiService) CreateReview(poiId, userId string, stars int, text string, visitedOn *time.Time) (*Review, error) {
+	if err := validateReview(stars, text, visitedOn); err != nil {
+		return nil, err
+	}
+	if p.store.GetPoiById(poiId) == nil {
+		return nil, ErrPoiNotFound
+	}
+	if p.store.GetUserReview(poiId, userId) != nil {
+		return nil, ErrAlreadyReviewed
+	}
+
+	now := time.Now().UTC()
+	review := Review{
+		ID:        uuid.New().String(),
+		PoiId:     poiId,
+		CreatedOn: now,
+		UpdatedOn: now,
+		CreatedBy: userId,
+		Stars:     stars,
+		Text:      strings.TrimSpace(text),
+		VisitedOn: visitedOn,
+	}
+	if err := p.store.CreateReview(&review); err != nil {
+		if errors.Is(err, ErrAlreadyReviewed) {
+			return nil, err
+		}
+		p.logger.Error("not able to create review", slog.String("poiId", poiId), slog.Any("err", err))
+		return nil, err
+	}
+
+	return &review, nil
+}
+
+func (p *PoiService) UpdateReview(poiId, id, userId string, stars int, text string, visitedOn *time.Time) (*Review, error) {
+	if err := validateReview(stars, text, visitedOn); err != nil {
+		return nil, err
+	}
+	review := p.store.GetReviewById(id)
+	if review == nil || review.PoiId != poiId {
+		return nil, ErrReviewNotFound
+	}
+	if review.CreatedBy != userId {
+		return nil, ErrNotReviewAuthor
+	}
+
+	review.Stars = stars
+	review.Text = strings.TrimSpace(text)
+	review.VisitedOn = visitedOn
+	review.UpdatedOn = time.Now().UTC()
+	if err := p.store.UpdateReview(review); err != nil {
+		p.logger.Error("not able to update review", slog.String("id", id), slog.Any("err", err))
+		return nil, err
+	}
+
+	return review, nil
+}
+
+// Moderators can delete any review, other users only their own
+func (p *PoiService) DeleteReview(poiId, id, userId string, moderator bool) error {
+	review := p.store.GetReviewById(id)
+	if review == nil || review.PoiId != poiId {
+		return ErrReviewNotFound
+	}
+	if review.CreatedBy != userId && !moderator {
+		return ErrNotReviewAuthor
+	}
+
+	if err := p.store.DeleteReview(*review); err != nil {
+		p.logger.Error("not able to delete review", slog.String("id", id), slog.Any("err", err))
+		return err
+	}
+	return nil
+}
+
+func validateReview(stars int, text string, visitedOn *time.Time) error {
+	if stars < MinStars || stars > MaxStars {
+		return ErrInvalidStars
+	}
+	if len(text) > maxReviewLength {
+		return ErrReviewTooLong
+	}
+	if visitedOn != nil && visitedOn.After(time.Now()) {
+		return ErrVisitInFuture
+	}
+	return nil
+}
+
+// Existing places likely to be the one described, most likely first
+func (p *PoiService) FindDuplicates(name, address, cityId string, latitude, longitude *float64, excludeId string) []DuplicateCandidate {
+	var candidates []DuplicateCandidate
+	for _, candidate := range p.store.GetDuplicateCandidates(cityId, latitude, longitude, 200) {
+		if candidate.ID == excludeId {
+			continue
+		}
+		if score := duplicateScore(name, address, latitude, longitude, candidate); score >= duplicateThreshold {
+			candidates = append(candidates, DuplicateCandidate{Poi: candidate, Score: score})
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].Score > candidates[j].Score
+	})
+	if len(candidates) > maxDuplicates {
+		candidates = candidates[:maxDuplicates]
+	}
+	return candidates
+}
+
+// Merges the duplicate into the place kept, the duplicate is deleted and its
+// url redirects to the place kept
+func (p *PoiService) MergePoi(id, intoId, mergedBy string) (*Poi, error) {
+	if id == intoId {
+		return nil, ErrMergeIntoSelf
+	}
+	if p.store.GetPoiById(id) == nil || p.store.GetPoiById(intoId) == nil {
+		return nil, ErrPoiNotFound
+	}
+
+	if err := p.store.MergePoi(id, intoId, mergedBy); err != nil {
+		p.logger.Error("not able to merge poi", slog.String("id", id), slog.String("into", intoId), slog.Any("err", err))
+		return nil, err
+	}
+
+	return p.store.GetPoiById(intoId), nil
+}
+
+// The place a deleted place ended up merged into, following merges of merged places
+func (p *PoiService) GetMergedPoi(id string) *Poi {
+	for i := 0; i < 5; i++ {
+		mergedInto := p.store.GetMergedInto(id)
+		if mergedInto == nil {
+			return nil
+		}
+		if poi := p.store.GetPoiById(*mergedInto); poi != nil {
+			return poi
+		}
+		id = *mergedInto
+	}
+	return nil
+}
+
+func (p *PoiService) GetPendingPois(limit int) []Poi {
+	return p.store.GetPendingPois(limit)
+}
+
+func (p *PoiService) ApprovePoi(id, moderatedBy string) (*Poi, error) {
+	return p.moderatePoi(id, moderatedBy, PoiApproved, nil)
+}
+
+func (p *PoiService) RejectPoi(id, moderatedBy, reason string) (*Poi, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, ErrNoRejectReason
+	}
+	return p.moderatePoi(id, moderatedBy, PoiRejected, &reason)
+}
+
+func (p *PoiService) moderatePoi(id, moderatedBy string, status PoiStatus, rejectionReason *string) (*Poi, error) {
+	if p.store.GetPoiById(id) == nil {
+		return nil, ErrPoiNotFound
+	}
+
+	moderated, err := p.store.ModeratePoi(id, moderatedBy, status, rejectionReason)
+	if err != nil {
+		p.logger.Error("not able to moderate poi", slog.String("id", id), slog.Any("err", err))
+		return nil, err
+	}
+	if !moderated {
+		return nil, ErrPoiNotPending
+	}
+
+	p.logger.Info("poi moderated", slog.String("id", id), slog.String("status", string(status)), slog.String("moderatedBy", moderatedBy))
+	return p.store.GetPoiById(id), nil
+}
+
+// Only approved places are listed, plus the pending ones of the viewer
+func (p *PoiService) SearchPois(cityId, sport, viewerId string, sortBy PoiSort, cursor string, pageSize int) Pois {
+	if sortBy == SortRating {
+		offset, _ := strconv.Atoi(cursor)
+
+		pois := p.store.GetPoisByRating(cityId, sport, viewerId, offset, pageSize+1)
+		nextCursor := ""
+		if len(pois) > pageSize {
+			nextCursor = strconv.Itoa(offset + pageSize)
+			pois = pois[:pageSize]
+		}
+
+		return Pois{
+			Results: pois,
+			Cursor:  nextCursor,
+		}
+	}
 
-func (p *PoiService) SearchPois(cityId, sport, cursor string, pageSize int) Pois {
 	internalCursor := p.getInternalCursor(cursor)
 
-	pois := p.store.GetPois(cityId, sport, internalCursor, pageSize+1)
+	pois := p.store.GetPois(cityId, sport, viewerId, internalCursor, pageSize+1)
 	nextCursor := ""
 	if len(pois) > pageSize {
 		nextCursor = pois[len(pois)-1].ID
@@ -45,6 +776,53 @@ func (p *PoiService) SearchPois(cityId, sport, cursor string, pageSize int) Pois
 	}
 }
 
+// Cursor of a distance search is the offset of the next page
+func (p *PoiService) SearchPoisNearby(latitude, longitude, radiusKm float64, sport, viewerId string, sortBy PoiSort, cursor string, pageSize int) Pois {
+	offset, _ := strconv.Atoi(cursor)
+
+	pois := p.store.GetPoisNearby(latitude, longitude, radiusKm, sport, viewerId, sortBy, offset, pageSize+1)
+	nextCursor := ""
+	if len(pois) > pageSize {
+		nextCursor = strconv.Itoa(offset + pageSize)
+		pois = pois[:pageSize]
+	}
+
+	return Pois{
+		Results: pois,
+		Cursor:  nextCursor,
+	}
+}
+
+// Cursor of a full-text search is the offset of the next page
+func (p *PoiService) FullTextSearch(query string, filters SearchFilters, cursor string, pageSize int) Pois {
+	offset, _ := strconv.Atoi(cursor)
+
+	pois := p.store.FullTextSearch(query, filters, offset, pageSize+1)
+	nextCursor := ""
+	if len(pois) > pageSize {
+		nextCursor = strconv.Itoa(offset + pageSize)
+		pois = pois[:pageSize]
+	}
+
+	return Pois{
+		Results: pois,
+		Cursor:  nextCursor,
+	}
+}
+
+// Returns the places in the viewport, or grid clusters of them when there are too many to draw
+func (p *PoiService) SearchPoisInBoundingBox(bbox BoundingBox, sport, viewerId string) MapPois {
+	if p.store.CountPoisInBoundingBox(bbox, sport, viewerId) > maxMapPois {
+		return MapPois{
+			Clusters: p.store.GetPoiClustersInBoundingBox(bbox, sport, viewerId, mapClusterGrid),
+		}
+	}
+
+	return MapPois{
+		Pois: p.store.GetPoisInBoundingBox(bbox, sport, viewerId, maxMapPois),
+	}
+}
+
 func (p *PoiService) getInternalCursor(cursor string) uint {
 	if cursor == "" {
 		return p.store.GetLatestPoiInternalId()
@@ -1,11 +1,18 @@
 package poi
 
 import (
+	"errors"
+	"fmt"
 	"log/slog"
+	"math"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/sportspazz/imaging"
+	"github.com/sportspazz/service/sport"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type PoiStore struct {
@@ -20,7 +27,13 @@ func NewPoiStore(db *gorm.DB, logger *slog.Logger) *PoiStore {
 	}
 }
 
-func (s *PoiStore) CreatePoi(createdBy, name, description, address, cityId string, googlePlaceId *string, website, sportType, thumbnailUrl, note string) Poi {
+const (
+	earthRadiusKm = 6371.0
+	// Postgres error code of duplicate values in a unique index
+	uniqueViolationCode = "23505"
+)
+
+func (s *PoiStore) CreatePoi(createdBy, name, description, address, cityId string, googlePlaceId *string, latitude, longitude *float64, website string, sports []sport.Sport, thumbnail Renditions, thumbnailImport *ThumbnailImport, note string, status PoiStatus) (Poi, error) {
 	now := time.Now().UTC()
 	poi := Poi{
 		ID:            uuid.New().String(),
@@ -33,17 +46,191 @@ func (s *PoiStore) CreatePoi(createdBy, name, description, address, cityId strin
 		Website:       website,
 		CityId:        cityId,
 		GooglePlaceId: googlePlaceId,
-		SportType:     sportType,
+		Latitude:      latitude,
+		Longitude:     longitude,
+		SportType:     sports[0].Name,
+		Sports:        sportNames(sports),
 		Description:   description,
-		ThumbnailUrl:  thumbnailUrl,
+		ThumbnailUrl:  imaging.JpegUrl(thumbnail, imaging.FallbackWidth),
 		Note:          note,
+		Status:        status,
 	}
+	poi.ThumbnailRenditions = thumbnail
 
-	if err := s.db.Create(poi).Error; err != nil {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(poi).Error; err != nil {
+			return err
+		}
+		if err := addPoiSports(tx, poi.ID, sports); err != nil {
+			return err
+		}
+		if thumbnailImport != nil {
+			if err := queueThumbnailImport(tx, poi.ID, createdBy, thumbnailImport); err != nil {
+				return err
+			}
+		}
+		return recordRevision(tx, poi.ID, createdBy, RevisionCreate)
+	})
+	if err != nil {
 		s.logger.Error("not able to create a new poi", slog.Any("err", err))
+		return Poi{}, err
+	}
+
+	return poi, nil
+}
+
+func (s *PoiStore) AddPoiSports(id, updatedBy string, sports []sport.Sport) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := addPoiSports(tx, id, sports); err != nil {
+			return err
+		}
+		if err := tx.Model(&Poi{}).
+			Where("id = ?", id).
+			Updates(map[string]interface{}{
+				"updated_by": updatedBy,
+				"updated_on": time.Now().UTC(),
+			}).Error; err != nil {
+			return err
+		}
+		return recordRevision(tx, id, updatedBy, RevisionUpdate)
+	})
+}
+
+func addPoiSports(tx *gorm.DB, poiId string, sports []sport.Sport) error {
+	poiSports := make([]PoiSport, 0, len(sports))
+	for _, sport := range sports {
+		poiSports = append(poiSports, PoiSport{PoiId: poiId, SportId: sport.ID})
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&poiSports).Error
+}
+
+// Fills in the sport names of the given pois
+func (s *PoiStore) withSports(pois []Poi) []Poi {
+	if len(pois) == 0 {
+		return pois
+	}
+
+	ids := make([]string, 0, len(pois))
+	for _, poi := range pois {
+		ids = append(ids, poi.ID)
+	}
+
+	var rows []struct {
+		PoiId string
+		Name  string
+	}
+	if err := s.db.Table("poi_sports").
+		Select("poi_sports.poi_id, sports.name").
+		Joins("JOIN sports ON sports.id = poi_sports.sport_id").
+		Where("poi_sports.poi_id IN ?", ids).
+		Order("sports.name").
+		Scan(&rows).Error; err != nil {
+		s.logger.Error("not able to load sports of pois", slog.Any("err", err))
+		return pois
+	}
+
+	sportsByPoi := make(map[string][]string)
+	for _, row := range rows {
+		sportsByPoi[row.PoiId] = append(sportsByPoi[row.PoiId], row.Name)
+	}
+	for i := range pois {
+		pois[i].Sports = sportsByPoi[pois[i].ID]
 	}
+	return pois
+}
 
-	return poi
+func withSport(sport string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if sport == "" {
+			return db
+		}
+		return db.Where(`EXISTS (SELECT 1 FROM poi_sports JOIN sports ON sports.id = poi_sports.sport_id
+			WHERE poi_sports.poi_id = pois.id AND (sports.slug = LOWER(?) OR LOWER(sports.name) = LOWER(?)))`, sport, sport)
+	}
+}
+
+// Approved places, plus the ones submitted by the viewer. Hidden places are
+// not listed to anyone.
+func visibleTo(viewerId string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		db = db.Where("pois.hidden_on IS NULL")
+		if viewerId == "" {
+			return db.Where("pois.status = ?", PoiApproved)
+		}
+		return db.Where("(pois.status = ? OR (pois.status = ? AND pois.created_by = ?))", PoiApproved, PoiPending, viewerId)
+	}
+}
+
+// Snapshots the place as it is after the change made in the transaction
+func recordRevision(tx *gorm.DB, poiId, createdBy string, action RevisionAction) error {
+	var poi Poi
+	if err := tx.First(&poi, "id = ?", poiId).Error; err != nil {
+		return err
+	}
+
+	sports := []string{}
+	if err := tx.Table("poi_sports").
+		Joins("JOIN sports ON sports.id = poi_sports.sport_id").
+		Where("poi_sports.poi_id = ?", poiId).
+		Order("sports.name").
+		Pluck("sports.name", &sports).Error; err != nil {
+		return err
+	}
+
+	return tx.Create(&PoiRevision{
+		ID:        uuid.New().String(),
+		PoiId:     poiId,
+		CreatedOn: time.Now().UTC(),
+		CreatedBy: createdBy,
+		Action:    action,
+		Snapshot: PoiSnapshot{
+			Name:                  poi.Name,
+			Address:               poi.Address,
+			Website:               poi.Website,
+			CityId:                poi.CityId,
+			Latitude:              poi.Latitude,
+			Longitude:             poi.Longitude,
+			SportType:             poi.SportType,
+			Sports:                sports,
+			ThumbnailUrl:          poi.ThumbnailUrl,
+			ThumbnailAttributions: poi.ThumbnailAttributions,
+			Description:           poi.Description,
+			Note:                  poi.Note,
+			Status:                poi.Status,
+			RejectionReason:       poi.RejectionReason,
+			Deleted:               poi.DeletedOn != nil,
+			Hidden:                poi.HiddenOn != nil,
+		},
+	}).Error
+}
+
+// Oldest first
+func (s *PoiStore) GetPoiRevisions(poiId string) []PoiRevision {
+	var revisions []PoiRevision
+	if err := s.db.Where("poi_id = ?", poiId).
+		Order("internal_id").
+		Find(&revisions).Error; err != nil {
+		s.logger.Error("not able to load poi revisions", slog.String("poiId", poiId), slog.Any("err", err))
+	}
+	return revisions
+}
+
+func (s *PoiStore) GetPoiRevision(poiId, id string) *PoiRevision {
+	var revision PoiRevision
+	result := s.db.First(&revision, "poi_id = ? AND id = ?", poiId, id)
+
+	if result.Error != nil {
+		return nil
+	}
+	return &revision
+}
+
+func sportNames(sports []sport.Sport) []string {
+	names := make([]string, 0, len(sports))
+	for _, sport := range sports {
+		names = append(names, sport.Name)
+	}
+	return names
 }
 
 func (s *PoiStore) GetInternalCursor(cursor string) (uint, error) {
@@ -71,17 +258,27 @@ func (s *PoiStore) GetLatestPoiInternalId() uint {
 
 func (s *PoiStore) GetPoiByGooglePlaceId(googlePlaceId string) *Poi {
 	var poi Poi
-	result := s.db.First(&poi, "google_place_id = ?", googlePlaceId)
+	result := s.db.First(&poi, "google_place_id = ? AND deleted_on IS NULL", googlePlaceId)
 
 	if result.Error != nil {
 		return nil
 	}
-	return &poi
+	return &s.withSports([]Poi{poi})[0]
 }
 
 func (s *PoiStore) GetPoiById(id string) *Poi {
 	var poi Poi
-	result := s.db.First(&poi, "id = ?", id)
+	result := s.db.First(&poi, "id = ? AND deleted_on IS NULL", id)
+
+	if result.Error != nil {
+		return nil
+	}
+	return &s.withSports([]Poi{poi})[0]
+}
+
+func (s *PoiStore) GetDeletedPoiById(id string) *Poi {
+	var poi Poi
+	result := s.db.First(&poi, "id = ? AND deleted_on IS NOT NULL", id)
 
 	if result.Error != nil {
 		return nil
@@ -89,13 +286,851 @@ func (s *PoiStore) GetPoiById(id string) *Poi {
 	return &poi
 }
 
-func (s *PoiStore) GetPois(cityId, sport string, cursor uint, pageSize int) []Poi {
+func (s *PoiStore) GetPois(cityId, sport, viewerId string, cursor uint, pageSize int) []Poi {
 	var pois []Poi
-	s.db.Where("city_id = ? AND sport_type = ? AND internal_id <= ?",
-		cityId, sport, cursor).
+	s.db.Where("city_id = ? AND internal_id <= ? AND deleted_on IS NULL", cityId, cursor).
+		Scopes(withSport(sport), visibleTo(viewerId)).
 		Order("internal_id DESC").
 		Limit(pageSize).
 		Find(&pois)
 
+	return s.withSports(pois)
+}
+
+// Best rated first, unrated places last. Ratings change between pages, so
+// pages are offsets rather than cursors.
+func (s *PoiStore) GetPoisByRating(cityId, sport, viewerId string, offset, pageSize int) []Poi {
+	var pois []Poi
+	s.db.Where("city_id = ? AND deleted_on IS NULL", cityId).
+		Scopes(withSport(sport), visibleTo(viewerId)).
+		Order(sortOrder(SortRating, "", "internal_id DESC")).
+		Offset(offset).
+		Limit(pageSize).
+		Find(&pois)
+
+	return s.withSports(pois)
+}
+
+// Prefixes the order of the search with the requested sort
+func sortOrder(sortBy PoiSort, table, order string) string {
+	if sortBy == SortRating {
+		return fmt.Sprintf("%[1]srating DESC NULLS LAST, %[1]srating_count DESC, %[2]s", table, order)
+	}
+	return order
+}
+
+// Pre-filters with a bounding box so the location index can be used, then
+// orders by the haversine distance in km.
+func (s *PoiStore) GetPoisNearby(latitude, longitude, radiusKm float64, sport, viewerId string, sortBy PoiSort, offset, pageSize int) []Poi {
+	latDelta := radiusKm / earthRadiusKm * 180 / math.Pi
+	lngDelta := latDelta / math.Max(math.Cos(latitude*math.Pi/180), 0.01)
+
+	query := s.db.Model(&Poi{}).
+		Select(`*, ? * 2 * ASIN(SQRT(
+			POWER(SIN(RADIANS(latitude - ?) / 2), 2) +
+			COS(RADIANS(?)) * COS(RADIANS(latitude)) * POWER(SIN(RADIANS(longitude - ?) / 2), 2)
+		)) AS distance_km`, earthRadiusKm, latitude, latitude, longitude).
+		Where("deleted_on IS NULL").
+		Where("latitude BETWEEN ? AND ?", latitude-latDelta, latitude+latDelta).
+		Where("longitude BETWEEN ? AND ?", longitude-lngDelta, longitude+lngDelta).
+		Scopes(withSport(sport), visibleTo(viewerId))
+
+	var pois []Poi
+	s.db.Table("(?) AS nearby", query).
+		Where("distance_km <= ?", radiusKm).
+		Order(sortOrder(sortBy, "", "distance_km, internal_id")).
+		Offset(offset).
+		Limit(pageSize).
+		Find(&pois)
+
+	return s.withSports(pois)
+}
+
+// Ranks matches by ts_rank over the weighted name, description and address vector
+func (s *PoiStore) FullTextSearch(query string, filters SearchFilters, offset, pageSize int) []Poi {
+	headlineOptions := fmt.Sprintf("StartSel=%s, StopSel=%s, MaxFragments=2, MaxWords=30, MinWords=10",
+		headlineStartSel, headlineStopSel)
+
+	search := s.db.Table("pois, websearch_to_tsquery('english', ?) AS query", query).
+		Select(`pois.*, ts_rank(pois.search_vector, query) AS rank,
+			ts_headline('english', coalesce(pois.description, ''), query, ?) AS headline`, headlineOptions).
+		Where("pois.search_vector @@ query AND pois.deleted_on IS NULL").
+		Scopes(withSport(filters.Sport), visibleTo(filters.ViewerId))
+	if filters.CityId != "" {
+		search = search.Where("pois.city_id = ?", filters.CityId)
+	}
+
+	var pois []Poi
+	search.Order(sortOrder(filters.Sort, "pois.", "rank DESC, pois.internal_id DESC")).
+		Offset(offset).
+		Limit(pageSize).
+		Find(&pois)
+
+	return s.withSports(pois)
+}
+
+func (s *PoiStore) inBoundingBox(bbox BoundingBox, sport, viewerId string) *gorm.DB {
+	return s.db.Model(&Poi{}).
+		Where("deleted_on IS NULL").
+		Where("latitude BETWEEN ? AND ?", bbox.MinLat, bbox.MaxLat).
+		Where("longitude BETWEEN ? AND ?", bbox.MinLng, bbox.MaxLng).
+		Scopes(withSport(sport), visibleTo(viewerId))
+}
+
+func (s *PoiStore) CountPoisInBoundingBox(bbox BoundingBox, sport, viewerId string) int64 {
+	var count int64
+	if err := s.inBoundingBox(bbox, sport, viewerId).Count(&count).Error; err != nil {
+		s.logger.Error("not able to count pois in bounding box", slog.Any("err", err))
+	}
+	return count
+}
+
+func (s *PoiStore) GetPoisInBoundingBox(bbox BoundingBox, sport, viewerId string, limit int) []Poi {
+	var pois []Poi
+	s.inBoundingBox(bbox, sport, viewerId).
+		Order("internal_id DESC").
+		Limit(limit).
+		Find(&pois)
+
+	return pois
+}
+
+// Groups the places of the bounding box into a gridSize x gridSize grid
+func (s *PoiStore) GetPoiClustersInBoundingBox(bbox BoundingBox, sport, viewerId string, gridSize int) []PoiCluster {
+	cellLat := math.Max((bbox.MaxLat-bbox.MinLat)/float64(gridSize), 1e-9)
+	cellLng := math.Max((bbox.MaxLng-bbox.MinLng)/float64(gridSize), 1e-9)
+
+	var clusters []PoiCluster
+	if err := s.inBoundingBox(bbox, sport, viewerId).
+		Select(`AVG(latitude) AS latitude, AVG(longitude) AS longitude, COUNT(*) AS count,
+			MIN(latitude) AS min_lat, MIN(longitude) AS min_lng, MAX(latitude) AS max_lat, MAX(longitude) AS max_lng`).
+		Group(fmt.Sprintf("FLOOR((latitude - %g) / %g), FLOOR((longitude - %g) / %g)", bbox.MinLat, cellLat, bbox.MinLng, cellLng)).
+		Scan(&clusters).Error; err != nil {
+		s.logger.Error("not able to cluster pois in bounding box", slog.Any("err", err))
+	}
+
+	return clusters
+}
+
+// Sports replace the current ones of the poi unless nil
+func (s *PoiStore) UpdatePoi(id, updatedBy string, update PoiUpdate, sports []sport.Sport) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return updatePoi(tx, id, updatedBy, update, sports, RevisionUpdate)
+	})
+}
+
+// Restores the content of a revision, the status of the place is kept
+func (s *PoiStore) RevertPoi(id, revertedBy string, update PoiUpdate, sports []sport.Sport) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return updatePoi(tx, id, revertedBy, update, sports, RevisionRevert)
+	})
+}
+
+func updatePoi(tx *gorm.DB, id, updatedBy string, update PoiUpdate, sports []sport.Sport, action RevisionAction) error {
+	updates := map[string]interface{}{
+		"updated_by": updatedBy,
+		"updated_on": time.Now().UTC(),
+	}
+	setIfPresent(updates, "name", update.Name)
+	setIfPresent(updates, "address", update.Address)
+	setIfPresent(updates, "website", update.Website)
+	setIfPresent(updates, "city_id", update.CityId)
+	setIfPresent(updates, "latitude", update.Latitude)
+	setIfPresent(updates, "longitude", update.Longitude)
+	if update.ThumbnailUrl != nil {
+		updates["thumbnail_url"] = *update.ThumbnailUrl
+		updates["thumbnail_renditions"] = update.ThumbnailRenditions
+		updates["thumbnail_attributions"] = update.ThumbnailAttributions
+	}
+	setIfPresent(updates, "description", update.Description)
+	setIfPresent(updates, "note", update.Note)
+
+	if sports != nil {
+		updates["sport_type"] = sports[0].Name
+	}
+
+	if err := tx.Model(&Poi{}).
+		Where("id = ? AND deleted_on IS NULL", id).
+		Updates(updates).Error; err != nil {
+		return err
+	}
+	if update.ThumbnailImport != nil {
+		if err := queueThumbnailImport(tx, id, updatedBy, update.ThumbnailImport); err != nil {
+			return err
+		}
+	}
+	if sports != nil {
+		if err := tx.Where("poi_id = ?", id).Delete(&PoiSport{}).Error; err != nil {
+			return err
+		}
+		if err := addPoiSports(tx, id, sports); err != nil {
+			return err
+		}
+	}
+	return recordRevision(tx, id, updatedBy, action)
+}
+
+// Oldest first, so places are reviewed in the order they were submitted
+func (s *PoiStore) GetPendingPois(limit int) []Poi {
+	var pois []Poi
+	s.db.Where("status = ? AND deleted_on IS NULL", PoiPending).
+		Order("created_on, internal_id").
+		Limit(limit).
+		Find(&pois)
+
+	return s.withSports(pois)
+}
+
+// Only pending places can be moderated, returns false when the place is
+// missing or was already reviewed
+func (s *PoiStore) ModeratePoi(id, moderatedBy string, status PoiStatus, rejectionReason *string) (bool, error) {
+	action := RevisionApprove
+	if status == PoiRejected {
+		action = RevisionReject
+	}
+
+	moderated := false
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&Poi{}).
+			Where("id = ? AND status = ? AND deleted_on IS NULL", id, PoiPending).
+			Updates(map[string]interface{}{
+				"status":           status,
+				"moderated_by":     moderatedBy,
+				"moderated_on":     time.Now().UTC(),
+				"rejection_reason": rejectionReason,
+			})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		moderated = true
+		return recordRevision(tx, id, moderatedBy, action)
+	})
+	return moderated, err
+}
+
+func (s *PoiStore) CreateSuggestion(suggestion *PoiEditSuggestion) error {
+	if err := s.db.Create(suggestion).Error; err != nil {
+		s.logger.Error("not able to create edit suggestion", slog.Any("err", err))
+		return err
+	}
+	return nil
+}
+
+func (s *PoiStore) GetSuggestionById(id string) *PoiEditSuggestion {
+	var suggestion PoiEditSuggestion
+	result := s.db.First(&suggestion, "id = ?", id)
+
+	if result.Error != nil {
+		return nil
+	}
+	return &suggestion
+}
+
+// Oldest first, suggestions for deleted places are left out
+func (s *PoiStore) GetPendingSuggestions(limit int) []PoiEditSuggestion {
+	var suggestions []PoiEditSuggestion
+	s.db.Joins("JOIN pois ON pois.id = poi_edit_suggestions.poi_id AND pois.deleted_on IS NULL").
+		Where("poi_edit_suggestions.status = ?", SuggestionPending).
+		Order("poi_edit_suggestions.created_on, poi_edit_suggestions.internal_id").
+		Limit(limit).
+		Find(&suggestions)
+
+	return suggestions
+}
+
+// Only pending suggestions can be reviewed, returns false when the
+// suggestion is missing or was already reviewed
+func (s *PoiStore) ReviewSuggestion(id, reviewedBy string, status SuggestionStatus, reviewNote *string) (bool, error) {
+	result := reviewSuggestion(s.db, id, reviewedBy, status, reviewNote)
+	return result.RowsAffected > 0, result.Error
+}
+
+// Claims the pending suggestion and applies its changes in the same
+// transaction, so concurrent reviews cannot apply it twice. Returns false
+// when the suggestion is missing or was already reviewed.
+func (s *PoiStore) AcceptSuggestion(suggestion *PoiEditSuggestion, reviewedBy string) (bool, error) {
+	accepted := false
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		result := reviewSuggestion(tx, suggestion.ID, reviewedBy, SuggestionAccepted, nil)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		accepted = true
+
+		changes := suggestion.Changes
+		if changes.Closed {
+			return deletePoi(tx, suggestion.PoiId, reviewedBy)
+		}
+		return updatePoi(tx, suggestion.PoiId, reviewedBy, PoiUpdate{
+			Name:        changes.Name,
+			Address:     changes.Address,
+			Website:     changes.Website,
+			Description: changes.Description,
+		}, nil, RevisionUpdate)
+	})
+	return accepted, err
+}
+
+func reviewSuggestion(tx *gorm.DB, id, reviewedBy string, status SuggestionStatus, reviewNote *string) *gorm.DB {
+	return tx.Model(&PoiEditSuggestion{}).
+		Where("id = ? AND status = ?", id, SuggestionPending).
+		Updates(map[string]interface{}{
+			"status":      status,
+			"reviewed_by": reviewedBy,
+			"reviewed_on": time.Now().UTC(),
+			"review_note": reviewNote,
+		})
+}
+
+func (s *PoiStore) CountSuggestionsSince(userId string, since time.Time) int64 {
+	var count int64
+	if err := s.db.Model(&PoiEditSuggestion{}).
+		Where("created_by = ? AND created_on >= ?", userId, since).
+		Count(&count).Error; err != nil {
+		s.logger.Error("not able to count edit suggestions", slog.String("userId", userId), slog.Any("err", err))
+	}
+	return count
+}
+
+func (s *PoiStore) GetSuggestionCounts(userId string) SuggestionCounts {
+	var rows []struct {
+		Status SuggestionStatus
+		Count  int64
+	}
+	if err := s.db.Model(&PoiEditSuggestion{}).
+		Select("status, COUNT(*) AS count").
+		Where("created_by = ?", userId).
+		Group("status").
+		Scan(&rows).Error; err != nil {
+		s.logger.Error("not able to count edit suggestions", slog.String("userId", userId), slog.Any("err", err))
+	}
+
+	var counts SuggestionCounts
+	for _, row := range rows {
+		switch row.Status {
+		case SuggestionPending:
+			counts.Pending = row.Count
+		case SuggestionAccepted:
+			counts.Accepted = row.Count
+		case SuggestionRejected:
+			counts.Rejected = row.Count
+		}
+	}
+	return counts
+}
+
+// Returns ErrAlreadyReported when the user reported the place concurrently
+func (s *PoiStore) CreateReport(report *PoiReport) error {
+	if err := s.db.Create(report).Error; err != nil {
+		if isUniqueViolation(err) {
+			return ErrAlreadyReported
+		}
+		s.logger.Error("not able to create poi report", slog.Any("err", err))
+		return err
+	}
+	return nil
+}
+
+func (s *PoiStore) HasOpenReport(poiId, userId string) bool {
+	var count int64
+	s.db.Model(&PoiReport{}).
+		Where("poi_id = ? AND created_by = ? AND resolved_on IS NULL", poiId, userId).
+		Count(&count)
+	return count > 0
+}
+
+func (s *PoiStore) CountReportsSince(userId string, since time.Time) int64 {
+	var count int64
+	if err := s.db.Model(&PoiReport{}).
+		Where("created_by = ? AND created_on >= ?", userId, since).
+		Count(&count).Error; err != nil {
+		s.logger.Error("not able to count poi reports", slog.String("userId", userId), slog.Any("err", err))
+	}
+	return count
+}
+
+func (s *PoiStore) CountOpenReports(poiId string) int64 {
+	var count int64
+	if err := s.db.Model(&PoiReport{}).
+		Where("poi_id = ? AND resolved_on IS NULL", poiId).
+		Count(&count).Error; err != nil {
+		s.logger.Error("not able to count poi reports", slog.String("poiId", poiId), slog.Any("err", err))
+	}
+	return count
+}
+
+// Newest first, reports of deleted places are left out
+func (s *PoiStore) GetOpenReports(limit int) []PoiReport {
+	var reports []PoiReport
+	s.db.Joins("JOIN pois ON pois.id = poi_reports.poi_id AND pois.deleted_on IS NULL").
+		Where("poi_reports.resolved_on IS NULL").
+		Order("poi_reports.internal_id DESC").
+		Limit(limit).
+		Find(&reports)
+
+	return reports
+}
+
+func (s *PoiStore) ResolveReports(poiId, resolvedBy string, resolution ReportResolution) error {
+	return s.db.Model(&PoiReport{}).
+		Where("poi_id = ? AND resolved_on IS NULL", poiId).
+		Updates(map[string]interface{}{
+			"resolved_on": time.Now().UTC(),
+			"resolved_by": resolvedBy,
+			"resolution":  resolution,
+		}).Error
+}
+
+// Returns false when the place was already in the requested state
+func (s *PoiStore) SetPoiHidden(id, updatedBy string, hidden bool) (bool, error) {
+	condition, hiddenOn, action := "hidden_on IS NULL", interface{}(time.Now().UTC()), RevisionHide
+	if !hidden {
+		condition, hiddenOn, action = "hidden_on IS NOT NULL", nil, RevisionUnhide
+	}
+
+	changed := false
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&Poi{}).
+			Where("id = ? AND deleted_on IS NULL AND "+condition, id).
+			Update("hidden_on", hiddenOn)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		changed = true
+		return recordRevision(tx, id, updatedBy, action)
+	})
+	return changed, err
+}
+
+// Photos are added after the existing ones of the place
+func (s *PoiStore) CreatePhoto(photo *PoiPhoto) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Raw("SELECT COALESCE(MAX(position), 0) + 1 FROM poi_photos WHERE poi_id = ?", photo.PoiId).
+			Scan(&photo.Position).Error; err != nil {
+			return err
+		}
+		return tx.Create(photo).Error
+	})
+}
+
+func (s *PoiStore) GetPhotoById(id string) *PoiPhoto {
+	var photo PoiPhoto
+	result := s.db.First(&photo, "id = ?", id)
+
+	if result.Error != nil {
+		return nil
+	}
+	return &photo
+}
+
+// Approved photos in gallery order, with the pending ones of the viewer
+func (s *PoiStore) GetPhotos(poiId, viewerId string, limit int) []PoiPhoto {
+	var photos []PoiPhoto
+	s.db.Where("poi_id = ?", poiId).
+		Where("status = ? OR (status = ? AND created_by = ?)", PhotoApproved, PhotoPending, viewerId).
+		Order("position, internal_id").
+		Limit(limit).
+		Find(&photos)
+
+	return photos
+}
+
+func (s *PoiStore) GetPendingPhotos(limit int) []PoiPhoto {
+	var photos []PoiPhoto
+	s.db.Joins("JOIN pois ON pois.id = poi_photos.poi_id AND pois.deleted_on IS NULL").
+		Where("poi_photos.status = ?", PhotoPending).
+		Order("poi_photos.created_on, poi_photos.internal_id").
+		Limit(limit).
+		Find(&photos)
+
+	return photos
+}
+
+func (s *PoiStore) ModeratePhoto(id, moderatedBy string, status PhotoStatus) (bool, error) {
+	result := s.db.Model(&PoiPhoto{}).
+		Where("id = ? AND status = ?", id, PhotoPending).
+		Updates(map[string]interface{}{
+			"status":       status,
+			"moderated_by": moderatedBy,
+			"moderated_on": time.Now().UTC(),
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// Moves the photo first in the gallery and makes it the thumbnail of the place
+func (s *PoiStore) SetCoverPhoto(photo PoiPhoto, updatedBy string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(`UPDATE poi_photos SET position = (
+				SELECT MIN(position) - 1 FROM poi_photos WHERE poi_id = ?
+			) WHERE id = ?`, photo.PoiId, photo.ID).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&Poi{}).
+			Where("id = ?", photo.PoiId).
+			Updates(map[string]interface{}{
+				"thumbnail_url":          photo.Url,
+				"thumbnail_renditions":   photo.Renditions,
+				"thumbnail_attributions": nil,
+				"updated_by":             updatedBy,
+				"updated_on":             time.Now().UTC(),
+			}).Error; err != nil {
+			return err
+		}
+		return recordRevision(tx, photo.PoiId, updatedBy, RevisionUpdate)
+	})
+}
+
+// Imports still pending for the place are skipped, the newest thumbnail wins
+func queueThumbnailImport(tx *gorm.DB, poiId, createdBy string, thumbnailImport *ThumbnailImport) error {
+	now := time.Now().UTC()
+	if err := tx.Model(&ThumbnailImport{}).
+		Where("poi_id = ? AND status = ?", poiId, ThumbnailImportPending).
+		Updates(map[string]interface{}{
+			"status":     ThumbnailImportSkipped,
+			"updated_on": now,
+		}).Error; err != nil {
+		return err
+	}
+
+	thumbnailImport.ID = uuid.New().String()
+	thumbnailImport.PoiId = poiId
+	thumbnailImport.CreatedOn = now
+	thumbnailImport.UpdatedOn = now
+	thumbnailImport.CreatedBy = createdBy
+	thumbnailImport.Status = ThumbnailImportPending
+	thumbnailImport.NextAttemptOn = now
+	return tx.Create(thumbnailImport).Error
+}
+
+// Claims due imports by pushing their next attempt out by lease, so other
+// instances skip them while they are being fetched
+func (s *PoiStore) ClaimDueThumbnailImports(limit int, lease time.Duration) ([]ThumbnailImport, error) {
+	now := time.Now().UTC()
+
+	var imports []ThumbnailImport
+	err := s.db.Raw(`
+		UPDATE thumbnail_imports SET attempts = attempts + 1, next_attempt_on = ?, updated_on = ?
+		WHERE internal_id IN (
+			SELECT internal_id FROM thumbnail_imports
+			WHERE status = ? AND next_attempt_on <= ?
+			ORDER BY next_attempt_on
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`, now.Add(lease), now, ThumbnailImportPending, now, limit).
+		Scan(&imports).Error
+
+	return imports, err
+}
+
+// Sets the imported thumbnail unless the place got another one in the
+// meantime, in which case the import is skipped and false is returned
+func (s *PoiStore) CompleteThumbnailImport(thumbnailImport ThumbnailImport, thumbnail Renditions) (bool, error) {
+	replaces := ""
+	if thumbnailImport.ReplacesThumbnailUrl != nil {
+		replaces = *thumbnailImport.ReplacesThumbnailUrl
+	}
+
+	applied := false
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now().UTC()
+		result := tx.Model(&Poi{}).
+			Where("id = ? AND COALESCE(thumbnail_url, '') = ?", thumbnailImport.PoiId, replaces).
+			Updates(map[string]interface{}{
+				"thumbnail_url":          imaging.JpegUrl(thumbnail, imaging.FallbackWidth),
+				"thumbnail_renditions":   thumbnail,
+				"thumbnail_attributions": thumbnailImport.Attributions,
+				"updated_by":             thumbnailImport.CreatedBy,
+				"updated_on":             now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		applied = result.RowsAffected > 0
+
+		status := ThumbnailImportSkipped
+		if applied {
+			status = ThumbnailImportDone
+			if err := recordRevision(tx, thumbnailImport.PoiId, thumbnailImport.CreatedBy, RevisionUpdate); err != nil {
+				return err
+			}
+		}
+		return tx.Model(&ThumbnailImport{}).
+			Where("id = ?", thumbnailImport.ID).
+			Updates(map[string]interface{}{
+				"status":      status,
+				"imported_on": now,
+				"updated_on":  now,
+			}).Error
+	})
+	if err != nil {
+		s.logger.Error("not able to complete thumbnail import", slog.String("id", thumbnailImport.ID), slog.Any("err", err))
+		return false, err
+	}
+	return applied, nil
+}
+
+func (s *PoiStore) MarkThumbnailImportFailed(id string, status ThumbnailImportStatus, nextAttemptOn time.Time, lastError string) error {
+	return s.db.Model(&ThumbnailImport{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status":          status,
+			"next_attempt_on": nextAttemptOn,
+			"last_error":      lastError,
+			"updated_on":      time.Now().UTC(),
+		}).Error
+}
+
+// Newest first
+func (s *PoiStore) GetReviews(poiId string, limit int) []Review {
+	var reviews []Review
+	s.db.Where("poi_id = ?", poiId).
+		Order("created_on DESC, internal_id DESC").
+		Limit(limit).
+		Find(&reviews)
+
+	return reviews
+}
+
+func (s *PoiStore) GetReviewById(id string) *Review {
+	var review Review
+	result := s.db.First(&review, "id = ?", id)
+
+	if result.Error != nil {
+		return nil
+	}
+	return &review
+}
+
+func (s *PoiStore) GetUserReview(poiId, userId string) *Review {
+	var review Review
+	result := s.db.First(&review, "poi_id = ? AND created_by = ?", poiId, userId)
+
+	if result.Error != nil {
+		return nil
+	}
+	return &review
+}
+
+// Returns ErrAlreadyReviewed when the user reviewed the place concurrently
+func (s *PoiStore) CreateReview(review *Review) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(review).Error; err != nil {
+			if isUniqueViolation(err) {
+				return ErrAlreadyReviewed
+			}
+			return err
+		}
+		return updateRating(tx, review.PoiId)
+	})
+}
+
+func (s *PoiStore) UpdateReview(review *Review) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&Review{}).
+			Where("id = ?", review.ID).
+			Updates(map[string]interface{}{
+				"stars":      review.Stars,
+				"text":       review.Text,
+				"visited_on": review.VisitedOn,
+				"updated_on": review.UpdatedOn,
+			}).Error; err != nil {
+			return err
+		}
+		return updateRating(tx, review.PoiId)
+	})
+}
+
+func (s *PoiStore) DeleteReview(review Review) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ?", review.ID).Delete(&Review{}).Error; err != nil {
+			return err
+		}
+		return updateRating(tx, review.PoiId)
+	})
+}
+
+// Recomputes the rating of the place from its reviews
+func updateRating(tx *gorm.DB, poiId string) error {
+	return tx.Exec(`UPDATE pois SET
+			rating = (SELECT AVG(stars) FROM reviews WHERE poi_id = ?),
+			rating_count = (SELECT COUNT(*) FROM reviews WHERE poi_id = ?)
+		WHERE id = ?`, poiId, poiId, poiId).Error
+}
+
+// Places near the location, or in the city when the location is unknown
+func (s *PoiStore) GetDuplicateCandidates(cityId string, latitude, longitude *float64, limit int) []Poi {
+	query := s.db.Where("deleted_on IS NULL")
+	if latitude != nil && longitude != nil {
+		latDelta := duplicateRadiusM / 1000 / earthRadiusKm * 180 / math.Pi
+		lngDelta := latDelta / math.Max(math.Cos(*latitude*math.Pi/180), 0.01)
+		query = query.
+			Where("latitude BETWEEN ? AND ?", *latitude-latDelta, *latitude+latDelta).
+			Where("longitude BETWEEN ? AND ?", *longitude-lngDelta, *longitude+lngDelta)
+	} else if cityId != "" {
+		query = query.Where("city_id = ?", cityId)
+	} else {
+		return nil
+	}
+
+	var pois []Poi
+	query.Order("internal_id DESC").
+		Limit(limit).
+		Find(&pois)
+
 	return pois
 }
+
+// Folds the source into the target: sports are combined, empty fields of the
+// target are filled from the source, and the source is deleted pointing to the target
+func (s *PoiStore) MergePoi(sourceId, targetId, mergedBy string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var source, target Poi
+		if err := tx.First(&source, "id = ? AND deleted_on IS NULL", sourceId).Error; err != nil {
+			return err
+		}
+		if err := tx.First(&target, "id = ? AND deleted_on IS NULL", targetId).Error; err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+		if err := tx.Model(&Poi{}).
+			Where("id = ?", sourceId).
+			Updates(map[string]interface{}{
+				"deleted_on":  now,
+				"merged_into": targetId,
+				"updated_by":  mergedBy,
+				"updated_on":  now,
+			}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Exec(`INSERT INTO poi_sports (poi_id, sport_id)
+			SELECT ?, sport_id FROM poi_sports WHERE poi_id = ? ON CONFLICT DO NOTHING`, targetId, sourceId).Error; err != nil {
+			return err
+		}
+
+		updates := map[string]interface{}{
+			"updated_by": mergedBy,
+			"updated_on": now,
+		}
+		if target.GooglePlaceId == nil && source.GooglePlaceId != nil {
+			updates["google_place_id"] = source.GooglePlaceId
+		}
+		if target.Latitude == nil && source.Latitude != nil {
+			updates["latitude"] = source.Latitude
+			updates["longitude"] = source.Longitude
+		}
+		fillIfEmpty(updates, "address", target.Address, source.Address)
+		fillIfEmpty(updates, "website", target.Website, source.Website)
+		fillIfEmpty(updates, "description", target.Description, source.Description)
+		if target.ThumbnailUrl == "" && source.ThumbnailUrl != "" {
+			updates["thumbnail_url"] = source.ThumbnailUrl
+			updates["thumbnail_renditions"] = source.ThumbnailRenditions
+			updates["thumbnail_attributions"] = source.ThumbnailAttributions
+		}
+		if err := tx.Model(&Poi{}).Where("id = ?", targetId).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		// the merge settles the reports of the source
+		if err := tx.Model(&PoiReport{}).
+			Where("poi_id = ? AND resolved_on IS NULL", sourceId).
+			Updates(map[string]interface{}{
+				"resolved_on": now,
+				"resolved_by": mergedBy,
+				"resolution":  ReportActioned,
+			}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Exec(`UPDATE poi_photos SET poi_id = ?,
+			position = position + (SELECT COALESCE(MAX(position), 0) FROM poi_photos WHERE poi_id = ?)
+			WHERE poi_id = ?`, targetId, targetId, sourceId).Error; err != nil {
+			return err
+		}
+
+		// reviews move unless their author already reviewed the place kept
+		if err := tx.Exec(`UPDATE reviews SET poi_id = ? WHERE poi_id = ?
+			AND created_by NOT IN (SELECT created_by FROM reviews WHERE poi_id = ?)`, targetId, sourceId, targetId).Error; err != nil {
+			return err
+		}
+		if err := updateRating(tx, sourceId); err != nil {
+			return err
+		}
+		if err := updateRating(tx, targetId); err != nil {
+			return err
+		}
+
+		if err := recordRevision(tx, sourceId, mergedBy, RevisionMerge); err != nil {
+			return err
+		}
+		return recordRevision(tx, targetId, mergedBy, RevisionMerge)
+	})
+}
+
+// The place a deleted place was merged into, nil when it was not merged
+func (s *PoiStore) GetMergedInto(id string) *string {
+	var poi Poi
+	result := s.db.First(&poi, "id = ? AND deleted_on IS NOT NULL", id)
+
+	if result.Error != nil {
+		return nil
+	}
+	return poi.MergedInto
+}
+
+func fillIfEmpty(updates map[string]interface{}, column, current, value string) {
+	if current == "" && value != "" {
+		updates[column] = value
+	}
+}
+
+func (s *PoiStore) DeletePoi(id, deletedBy string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return deletePoi(tx, id, deletedBy)
+	})
+}
+
+func deletePoi(tx *gorm.DB, id, deletedBy string) error {
+	now := time.Now().UTC()
+	if err := tx.Model(&Poi{}).
+		Where("id = ? AND deleted_on IS NULL", id).
+		Updates(map[string]interface{}{
+			"deleted_on": now,
+			"updated_by": deletedBy,
+			"updated_on": now,
+		}).Error; err != nil {
+		return err
+	}
+	return recordRevision(tx, id, deletedBy, RevisionDelete)
+}
+
+func (s *PoiStore) RestorePoi(id, restoredBy string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&Poi{}).
+			Where("id = ? AND deleted_on IS NOT NULL", id).
+			Updates(map[string]interface{}{
+				"deleted_on":  nil,
+				"merged_into": nil,
+				"updated_by":  restoredBy,
+				"updated_on":  time.Now().UTC(),
+			}).Error; err != nil {
+			return err
+		}
+		return recordRevision(tx, id, restoredBy, RevisionRestore)
+	})
+}
+
+func setIfPresent[T any](updates map[string]interface{}, column string, value *T) {
+	if value != nil {
+		updates[column] = *value
+	}
+}
+
+// Concurrent inserts checked beforehand can still collide on a unique index
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode
+}
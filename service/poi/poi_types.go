@@ -1,12 +1,36 @@
 package poi
 
-import "time"
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/sportspazz/imaging"
+)
+
+// Delimit the matched terms in full-text search headlines
+const (
+	headlineStartSel = "\ue000"
+	headlineStopSel  = "\ue001"
+)
+
+type PoiStatus string
+
+const (
+	PoiPending  PoiStatus = "pending"
+	PoiApproved PoiStatus = "approved"
+	PoiRejected PoiStatus = "rejected"
+)
 
 type Poi struct {
 	internalId    uint `gorm:"primaryKey"`
 	ID            string
-	CreatedOn     time.Time `gorm:"type:timestamp(3) without time zone" json:"created_on"`
-	UpdatedOn     time.Time `gorm:"type:timestamp(3) without time zone" json:"updated_on"`
+	CreatedOn     time.Time  `gorm:"type:timestamp(3) without time zone" json:"created_on"`
+	UpdatedOn     time.Time  `gorm:"type:timestamp(3) without time zone" json:"updated_on"`
+	DeletedOn     *time.Time `gorm:"type:timestamp(3) without time zone" json:"deleted_on"`
 	CreatedBy     string
 	UpdatedBy     string
 	Name          string
@@ -14,13 +38,507 @@ type Poi struct {
 	Website       string
 	CityId        string
 	GooglePlaceId *string
-	SportType     string
-	ThumbnailUrl  string
-	Description   string
-	Note          string
+	Latitude      *float64
+	Longitude     *float64
+	// primary sport, used in place urls
+	SportType    string
+	Sports       []string `gorm:"-" json:"sports"`
+	ThumbnailUrl string
+	// empty for thumbnails linked from other sites
+	ThumbnailRenditions Renditions `gorm:"type:jsonb"`
+	// html credits to show next to the thumbnail, set for Google photos
+	ThumbnailAttributions Attributions `gorm:"type:jsonb"`
+	Description           string
+	Note                  string
+	// places of non-trusted users are pending until a moderator reviews them
+	Status          PoiStatus
+	ModeratedBy     *string
+	ModeratedOn     *time.Time `gorm:"type:timestamp(3) without time zone"`
+	RejectionReason *string
+	// set when enough users reported the place, hidden places are not listed
+	HiddenOn *time.Time `gorm:"type:timestamp(3) without time zone"`
+	// set on deleted places that were merged into another one
+	MergedInto *string
+	// average stars of the reviews, nil until the place is reviewed
+	Rating      *float64
+	RatingCount int
+	// only populated by distance searches
+	DistanceKm *float64 `gorm:"->" json:"distance_km,omitempty"`
+	// only populated by full-text searches
+	Headline *string `gorm:"->" json:"-"`
+}
+
+// Only approved places that are not hidden are public, the submitter and
+// moderators also see the others
+func (p Poi) VisibleTo(userId string, moderator bool) bool {
+	return (p.Status == PoiApproved && p.HiddenOn == nil) || moderator || (userId != "" && p.CreatedBy == userId)
+}
+
+func (p Poi) Pending() bool {
+	return p.Status == PoiPending
+}
+
+type PoiSport struct {
+	PoiId   string
+	SportId string
+}
+
+type SnippetPart struct {
+	Text        string
+	Highlighted bool
+}
+
+// Splits the full-text search headline into plain and matched parts, so
+// they can be rendered without trusting the description as HTML.
+func (p Poi) Snippet() []SnippetPart {
+	if p.Headline == nil {
+		return nil
+	}
+
+	var parts []SnippetPart
+	rest := *p.Headline
+	for rest != "" {
+		start := strings.Index(rest, headlineStartSel)
+		if start < 0 {
+			parts = append(parts, SnippetPart{Text: rest})
+			break
+		}
+		if start > 0 {
+			parts = append(parts, SnippetPart{Text: rest[:start]})
+		}
+		rest = rest[start+len(headlineStartSel):]
+
+		stop := strings.Index(rest, headlineStopSel)
+		if stop < 0 {
+			stop = len(rest)
+		}
+		parts = append(parts, SnippetPart{Text: rest[:stop], Highlighted: true})
+		rest = strings.TrimPrefix(rest[stop:], headlineStopSel)
+	}
+	return parts
 }
 
 type Pois struct {
 	Results []Poi
 	Cursor  string
 }
+
+type SearchFilters struct {
+	CityId string
+	Sport  string
+	// pending places of the viewer are included
+	ViewerId string
+	Sort     PoiSort
+}
+
+type PoiCluster struct {
+	Latitude  float64
+	Longitude float64
+	Count     int
+	MinLat    float64
+	MinLng    float64
+	MaxLat    float64
+	MaxLng    float64
+}
+
+// Either Pois or Clusters is populated, depending on how many places are in the viewport
+type MapPois struct {
+	Pois     []Poi
+	Clusters []PoiCluster
+}
+
+type BoundingBox struct {
+	MinLat float64
+	MinLng float64
+	MaxLat float64
+	MaxLng float64
+}
+
+// Fields left nil are not changed
+type PoiUpdate struct {
+	Name         *string
+	Address      *string
+	Website      *string
+	CityId       *string
+	Latitude     *float64
+	Longitude    *float64
+	SportTypes   []string
+	ThumbnailUrl *string
+	// replace the renditions and attributions of the previous thumbnail when ThumbnailUrl is set
+	ThumbnailRenditions   Renditions
+	ThumbnailAttributions Attributions
+	// fetched in the background instead of setting ThumbnailUrl
+	ThumbnailImport *ThumbnailImport
+	Description     *string
+	Note            *string
+}
+
+type RevisionAction string
+
+const (
+	RevisionCreate  RevisionAction = "create"
+	RevisionUpdate  RevisionAction = "update"
+	RevisionDelete  RevisionAction = "delete"
+	RevisionRestore RevisionAction = "restore"
+	RevisionApprove RevisionAction = "approve"
+	RevisionReject  RevisionAction = "reject"
+	RevisionRevert  RevisionAction = "revert"
+	RevisionHide    RevisionAction = "hide"
+	RevisionUnhide  RevisionAction = "unhide"
+	RevisionMerge   RevisionAction = "merge"
+)
+
+// Author of the changes made automatically, such as hiding reported places
+const SystemUserId = "system"
+
+// Every change of a place is recorded with a full snapshot of its content
+type PoiRevision struct {
+	internalId uint `gorm:"primaryKey"`
+	ID         string
+	PoiId      string
+	CreatedOn  time.Time `gorm:"type:timestamp(3) without time zone"`
+	CreatedBy  string
+	Action     RevisionAction
+	Snapshot   PoiSnapshot `gorm:"type:jsonb"`
+	// difference to the previous revision, empty for the first one
+	Changes []FieldChange `gorm:"-"`
+}
+
+type PoiSnapshot struct {
+	Name                  string       `json:"name"`
+	Address               string       `json:"address"`
+	Website               string       `json:"website"`
+	CityId                string       `json:"city_id"`
+	Latitude              *float64     `json:"latitude"`
+	Longitude             *float64     `json:"longitude"`
+	SportType             string       `json:"sport_type"`
+	Sports                []string     `json:"sports"`
+	ThumbnailUrl          string       `json:"thumbnail_url"`
+	ThumbnailAttributions Attributions `json:"thumbnail_attributions"`
+	Description           string       `json:"description"`
+	Note                  string       `json:"note"`
+	Status                PoiStatus    `json:"status"`
+	RejectionReason       *string      `json:"rejection_reason"`
+	Deleted               bool         `json:"deleted"`
+	Hidden                bool         `json:"hidden"`
+}
+
+func (s PoiSnapshot) Value() (driver.Value, error) {
+	snapshot, err := json.Marshal(s)
+	return string(snapshot), err
+}
+
+func (s *PoiSnapshot) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, s)
+	case string:
+		return json.Unmarshal([]byte(v), s)
+	default:
+		return fmt.Errorf("cannot scan %T into poi snapshot", value)
+	}
+}
+
+type FieldChange struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+// Compares the snapshot field by field, fields are named after their JSON keys
+func (s PoiSnapshot) Diff(previous PoiSnapshot) []FieldChange {
+	current, prev := reflect.ValueOf(s), reflect.ValueOf(previous)
+
+	var changes []FieldChange
+	for i := 0; i < current.NumField(); i++ {
+		from, to := snapshotValue(prev.Field(i)), snapshotValue(current.Field(i))
+		if from != to {
+			field, _, _ := strings.Cut(current.Type().Field(i).Tag.Get("json"), ",")
+			changes = append(changes, FieldChange{Field: field, From: from, To: to})
+		}
+	}
+	return changes
+}
+
+func snapshotValue(value reflect.Value) string {
+	switch value.Kind() {
+	case reflect.Pointer:
+		if value.IsNil() {
+			return ""
+		}
+		return snapshotValue(value.Elem())
+	case reflect.Slice:
+		values := make([]string, value.Len())
+		for i := range values {
+			values[i] = snapshotValue(value.Index(i))
+		}
+		return strings.Join(values, ", ")
+	default:
+		return fmt.Sprint(value.Interface())
+	}
+}
+
+type SuggestionStatus string
+
+const (
+	SuggestionPending  SuggestionStatus = "pending"
+	SuggestionAccepted SuggestionStatus = "accepted"
+	SuggestionRejected SuggestionStatus = "rejected"
+)
+
+// Changes to a place proposed by a regular user, applied once a moderator accepts them
+type PoiEditSuggestion struct {
+	internalId uint `gorm:"primaryKey"`
+	ID         string
+	PoiId      string
+	CreatedOn  time.Time `gorm:"type:timestamp(3) without time zone"`
+	CreatedBy  string
+	Status     SuggestionStatus
+	Changes    SuggestedChanges `gorm:"type:jsonb"`
+	Comment    string
+	ReviewedBy *string
+	ReviewedOn *time.Time `gorm:"type:timestamp(3) without time zone"`
+	ReviewNote *string
+	// the place as it is now, only populated for review
+	Poi *Poi `gorm:"-"`
+}
+
+// Fields left nil are not changed, a closed place is deleted
+type SuggestedChanges struct {
+	Name        *string `json:"name,omitempty"`
+	Address     *string `json:"address,omitempty"`
+	Website     *string `json:"website,omitempty"`
+	Description *string `json:"description,omitempty"`
+	Closed      bool    `json:"closed,omitempty"`
+}
+
+func (c SuggestedChanges) Value() (driver.Value, error) {
+	changes, err := json.Marshal(c)
+	return string(changes), err
+}
+
+func (c *SuggestedChanges) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, c)
+	case string:
+		return json.Unmarshal([]byte(v), c)
+	default:
+		return fmt.Errorf("cannot scan %T into suggested changes", value)
+	}
+}
+
+func (c SuggestedChanges) IsEmpty() bool {
+	return c.Name == nil && c.Address == nil && c.Website == nil && c.Description == nil && !c.Closed
+}
+
+type SuggestionCounts struct {
+	Pending  int64
+	Accepted int64
+	Rejected int64
+}
+
+type Renditions []imaging.Rendition
+
+func (r Renditions) Value() (driver.Value, error) {
+	if r == nil {
+		return nil, nil
+	}
+	renditions, err := json.Marshal(r)
+	return string(renditions), err
+}
+
+func (r *Renditions) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*r = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, r)
+	case string:
+		return json.Unmarshal([]byte(v), r)
+	default:
+		return fmt.Errorf("cannot scan %T into renditions", value)
+	}
+}
+
+type Attributions []string
+
+func (a Attributions) Value() (driver.Value, error) {
+	if a == nil {
+		return nil, nil
+	}
+	attributions, err := json.Marshal(a)
+	return string(attributions), err
+}
+
+func (a *Attributions) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*a = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, a)
+	case string:
+		return json.Unmarshal([]byte(v), a)
+	default:
+		return fmt.Errorf("cannot scan %T into attributions", value)
+	}
+}
+
+type PhotoStatus string
+
+const (
+	PhotoPending  PhotoStatus = "pending"
+	PhotoApproved PhotoStatus = "approved"
+	PhotoRejected PhotoStatus = "rejected"
+)
+
+const (
+	maxPhotoCaptionLength = 200
+	maxPhotosListed       = 50
+)
+
+// Photo uploaded by a user, shown in the gallery of the place once approved
+type PoiPhoto struct {
+	internalId uint `gorm:"primaryKey"`
+	ID         string
+	PoiId      string
+	CreatedOn  time.Time `gorm:"type:timestamp(3) without time zone"`
+	CreatedBy  string
+	// original upload, only set on photos uploaded before renditions were made
+	ObjectName *string
+	Renditions Renditions `gorm:"type:jsonb"`
+	// fallback for browsers without srcset
+	Url         string
+	Caption     string
+	Position    int
+	Status      PhotoStatus
+	ModeratedBy *string
+	ModeratedOn *time.Time `gorm:"type:timestamp(3) without time zone"`
+	// only populated for the moderation queue
+	Poi *Poi `gorm:"-"`
+}
+
+func (p PoiPhoto) Pending() bool {
+	return p.Status == PhotoPending
+}
+
+type ThumbnailImportStatus string
+
+const (
+	ThumbnailImportPending ThumbnailImportStatus = "pending"
+	ThumbnailImportDone    ThumbnailImportStatus = "done"
+	// the place got another thumbnail before the import completed
+	ThumbnailImportSkipped ThumbnailImportStatus = "skipped"
+	ThumbnailImportFailed  ThumbnailImportStatus = "failed"
+)
+
+// Thumbnail of a place fetched and stored by the ThumbnailImporter. Google
+// photos are kept as a reference, the photo url holds our API key so it is
+// only built when fetching
+type ThumbnailImport struct {
+	internalId     uint `gorm:"primaryKey"`
+	ID             string
+	PoiId          string
+	CreatedOn      time.Time `gorm:"type:timestamp(3) without time zone"`
+	UpdatedOn      time.Time `gorm:"type:timestamp(3) without time zone"`
+	CreatedBy      string
+	SourceUrl      *string
+	PhotoReference *string
+	Attributions   Attributions `gorm:"type:jsonb"`
+	// thumbnail of the place when the import was queued, none for new places
+	ReplacesThumbnailUrl *string
+	Status               ThumbnailImportStatus
+	Attempts             int
+	NextAttemptOn        time.Time `gorm:"type:timestamp(3) without time zone"`
+	LastError            *string
+	ImportedOn           *time.Time `gorm:"type:timestamp(3) without time zone"`
+}
+
+type ReportReason string
+
+const (
+	ReportClosed         ReportReason = "permanently_closed"
+	ReportDuplicate      ReportReason = "duplicate"
+	ReportWrongSport     ReportReason = "wrong_sport"
+	ReportSpam           ReportReason = "spam"
+	ReportOffensiveImage ReportReason = "offensive_image"
+)
+
+var ReportReasons = []ReportReason{ReportClosed, ReportDuplicate, ReportWrongSport, ReportSpam, ReportOffensiveImage}
+
+func ParseReportReason(value string) (ReportReason, error) {
+	for _, reason := range ReportReasons {
+		if string(reason) == value {
+			return reason, nil
+		}
+	}
+	return "", fmt.Errorf("%w %q", ErrUnknownReportReason, value)
+}
+
+type ReportResolution string
+
+const (
+	ReportDismissed ReportResolution = "dismissed"
+	ReportActioned  ReportResolution = "actioned"
+)
+
+type PoiReport struct {
+	internalId  uint `gorm:"primaryKey"`
+	ID          string
+	PoiId       string
+	CreatedOn   time.Time `gorm:"type:timestamp(3) without time zone"`
+	CreatedBy   string
+	Reason      ReportReason
+	DuplicateOf *string
+	Comment     string
+	ResolvedOn  *time.Time `gorm:"type:timestamp(3) without time zone"`
+	ResolvedBy  *string
+	Resolution  *ReportResolution
+}
+
+type PoiSort string
+
+const (
+	// newest places first, or the most relevant ones for text and distance searches
+	SortDefault PoiSort = ""
+	SortRating  PoiSort = "rating"
+)
+
+func ParsePoiSort(value string) PoiSort {
+	if value == string(SortRating) {
+		return SortRating
+	}
+	return SortDefault
+}
+
+const (
+	MinStars         = 1
+	MaxStars         = 5
+	maxReviewLength  = 4000
+	maxReviewsListed = 100
+)
+
+type Review struct {
+	internalId uint `gorm:"primaryKey"`
+	ID         string
+	PoiId      string
+	CreatedOn  time.Time `gorm:"type:timestamp(3) without time zone"`
+	UpdatedOn  time.Time `gorm:"type:timestamp(3) without time zone"`
+	CreatedBy  string
+	Stars      int
+	Text       string
+	VisitedOn  *time.Time `gorm:"type:date"`
+}
+
+// Open reports of a place, grouped for review
+type ReportedPoi struct {
+	Poi       Poi
+	Reporters int
+	Reasons   map[ReportReason]int
+	// places it was reported to be a duplicate of
+	DuplicateOf  []Poi
+	Comments     []string
+	LastReportOn time.Time
+}
@@ -0,0 +1,283 @@
+package poi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sportspazz/blob"
+	"github.com/sportspazz/imaging"
+)
+
+const (
+	thumbnailPollInterval = 30 * time.Second
+	thumbnailBatchSize    = 10
+	// time given to fetch a claimed image before another attempt is made
+	thumbnailLease        = 5 * time.Minute
+	thumbnailFetchTimeout = 30 * time.Second
+	thumbnailMaxRedirects = 5
+	thumbnailMaxAttempts  = 6
+	thumbnailBaseBackoff  = 5 * time.Minute
+	thumbnailMaxBackoff   = 24 * time.Hour
+
+	googlePhotoURL      = "https://maps.googleapis.com/maps/api/place/photo"
+	googlePhotoMaxWidth = 1600
+)
+
+var (
+	errImageNotFound   = errors.New("image not found")
+	errInvalidImageUrl = errors.New("invalid image url")
+	errPrivateAddress  = errors.New("image url resolves to a private address")
+)
+
+// 100.64.0.0/10, used inside carrier and cloud networks
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// Errors not worth another attempt, the image itself is unusable
+var permanentImportErrors = []error{
+	imaging.ErrUnsupportedImage,
+	imaging.ErrImageTooLarge,
+	imaging.ErrFileTooLarge,
+	errImageNotFound,
+	errInvalidImageUrl,
+	errPrivateAddress,
+}
+
+// Thumbnail to import from sourceUrl or a Google Places photoReference, nil
+// when there is neither. Google photo urls are turned into their reference so
+// no API key is stored.
+func NewThumbnailImport(sourceUrl, photoReference string, attributions []string) *ThumbnailImport {
+	if photoReference == "" {
+		photoReference = googlePhotoReference(sourceUrl)
+	}
+
+	thumbnailImport := &ThumbnailImport{Attributions: attributions}
+	switch {
+	case photoReference != "":
+		thumbnailImport.PhotoReference = &photoReference
+	case sourceUrl != "":
+		thumbnailImport.SourceUrl = &sourceUrl
+	default:
+		return nil
+	}
+	return thumbnailImport
+}
+
+func googlePhotoReference(sourceUrl string) string {
+	parsed, err := url.Parse(sourceUrl)
+	if err != nil || parsed.Scheme+"://"+parsed.Host+parsed.Path != googlePhotoURL {
+		return ""
+	}
+	return parsed.Query().Get("photoreference")
+}
+
+// Fetches queued thumbnails, stores their renditions and sets them on the
+// places. Failures are retried with exponential backoff.
+type ThumbnailImporter struct {
+	store           *PoiStore
+	blobStore       blob.BlobStore
+	googleMapApiKey string
+	httpClient      *http.Client
+	logger          *slog.Logger
+}
+
+func NewThumbnailImporter(store *PoiStore, blobStore blob.BlobStore, googleMapApiKey string, logger *slog.Logger) *ThumbnailImporter {
+	return &ThumbnailImporter{
+		store:           store,
+		blobStore:       blobStore,
+		googleMapApiKey: googleMapApiKey,
+		httpClient:      newImageHttpClient(),
+		logger:          logger,
+	}
+}
+
+// Image urls come from users, the client only connects to public addresses
+// over http or https so they cannot reach services of the internal network.
+// The address is checked once resolved, redirects and DNS included.
+func newImageHttpClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   thumbnailFetchTimeout,
+		KeepAlive: 30 * time.Second,
+		Control:   refusePrivateAddress,
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// a proxy would connect on our behalf, past the check of the dialer
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   thumbnailFetchTimeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= thumbnailMaxRedirects {
+				return fmt.Errorf("stopped after %d redirects", thumbnailMaxRedirects)
+			}
+			if !isHttpUrl(req.URL) {
+				return errInvalidImageUrl
+			}
+			return nil
+		},
+	}
+}
+
+func refusePrivateAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+
+	// loopback, link-local, multicast and unspecified addresses are not global unicast
+	ip = ip.Unmap()
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() || sharedAddressSpace.Contains(ip) {
+		return errPrivateAddress
+	}
+	return nil
+}
+
+func isHttpUrl(u *url.URL) bool {
+	return u.Scheme == "http" || u.Scheme == "https"
+}
+
+// Imports queued thumbnails until ctx is done
+func (i *ThumbnailImporter) Run(ctx context.Context) {
+	ticker := time.NewTicker(thumbnailPollInterval)
+	defer ticker.Stop()
+
+	for {
+		i.importDueThumbnails(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (i *ThumbnailImporter) importDueThumbnails(ctx context.Context) {
+	for {
+		imports, err := i.store.ClaimDueThumbnailImports(thumbnailBatchSize, thumbnailLease)
+		if err != nil {
+			i.logger.Error("not able to claim thumbnail imports", slog.Any("err", err))
+			return
+		}
+
+		for _, thumbnailImport := range imports {
+			i.importThumbnail(ctx, thumbnailImport)
+		}
+		if len(imports) < thumbnailBatchSize || ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+func (i *ThumbnailImporter) importThumbnail(ctx context.Context, thumbnailImport ThumbnailImport) {
+	thumbnail, err := i.fetch(ctx, thumbnailImport)
+	if err == nil {
+		var applied bool
+		applied, err = i.store.CompleteThumbnailImport(thumbnailImport, thumbnail)
+		if err == nil {
+			if !applied {
+				i.logger.Info("place already has a thumbnail", slog.String("id", thumbnailImport.ID), slog.String("poiId", thumbnailImport.PoiId))
+				imaging.Delete(ctx, i.blobStore, thumbnail)
+			}
+			return
+		}
+		imaging.Delete(ctx, i.blobStore, thumbnail)
+	}
+
+	status, nextAttemptOn := ThumbnailImportPending, time.Now().UTC().Add(thumbnailBackoff(thumbnailImport.Attempts))
+	if thumbnailImport.Attempts >= thumbnailMaxAttempts || isPermanentImportError(err) {
+		status = ThumbnailImportFailed
+	}
+	i.logger.Warn("not able to import thumbnail",
+		slog.String("id", thumbnailImport.ID),
+		slog.String("poiId", thumbnailImport.PoiId),
+		slog.Int("attempts", thumbnailImport.Attempts),
+		slog.String("status", string(status)),
+		slog.Any("err", err))
+
+	if err := i.store.MarkThumbnailImportFailed(thumbnailImport.ID, status, nextAttemptOn, err.Error()); err != nil {
+		i.logger.Error("not able to reschedule thumbnail import", slog.String("id", thumbnailImport.ID), slog.Any("err", err))
+	}
+}
+
+// Downloads the image and stores its renditions
+func (i *ThumbnailImporter) fetch(ctx context.Context, thumbnailImport ThumbnailImport) (Renditions, error) {
+	var source string
+	switch {
+	case thumbnailImport.PhotoReference != nil:
+		source = googlePhotoURL + "?" + url.Values{
+			"maxwidth":       {strconv.Itoa(googlePhotoMaxWidth)},
+			"photoreference": {*thumbnailImport.PhotoReference},
+			"key":            {i.googleMapApiKey},
+		}.Encode()
+	case thumbnailImport.SourceUrl != nil:
+		source = *thumbnailImport.SourceUrl
+	default:
+		return nil, errInvalidImageUrl
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil || !isHttpUrl(req.URL) {
+		return nil, errInvalidImageUrl
+	}
+	resp, err := i.httpClient.Do(req)
+	if err != nil {
+		// url errors repeat the url, which holds our API key for Google photos
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return nil, fmt.Errorf("error downloading image: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return nil, errImageNotFound
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("error downloading image: status %d", resp.StatusCode)
+	}
+
+	data, err := imaging.Read(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	renditions, err := imaging.Process(data)
+	if err != nil {
+		return nil, err
+	}
+	return imaging.Store(ctx, i.blobStore, "poi/thumbnails/"+uuid.New().String(), renditions)
+}
+
+func isPermanentImportError(err error) bool {
+	for _, permanent := range permanentImportErrors {
+		if errors.Is(err, permanent) {
+			return true
+		}
+	}
+	return false
+}
+
+// 5, 10, 20, ... minutes after the given number of attempts, at most thumbnailMaxBackoff
+func thumbnailBackoff(attempts int) time.Duration {
+	delay := thumbnailBaseBackoff
+	for i := 1; i < attempts && delay < thumbnailMaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, thumbnailMaxBackoff)
+}
@@ -0,0 +1,112 @@
+package session
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	sessionTTL = 30 * 24 * time.Hour
+	// last seen is only written once per interval to spare the database
+	lastSeenResolution = time.Minute
+)
+
+var ErrSessionNotFound = errors.New("session not found")
+
+type SessionService struct {
+	store  *SessionStore
+	logger *slog.Logger
+}
+
+func NewSessionService(store *SessionStore, logger *slog.Logger) *SessionService {
+	return &SessionService{
+		store:  store,
+		logger: logger,
+	}
+}
+
+// Returns the opaque token to hand to the browser along with the new session
+func (s *SessionService) CreateSession(userId, userAgent, ipAddress string) (string, *Session, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, err
+	}
+	token := base64.RawURLEncoding.EncodeToString(secret)
+
+	now := time.Now().UTC()
+	session := &Session{
+		ID:         uuid.New().String(),
+		CreatedOn:  now,
+		LastSeenOn: now,
+		ExpiresOn:  now.Add(sessionTTL),
+		UserId:     userId,
+		TokenHash:  hashToken(token),
+		UserAgent:  truncate(userAgent, 512),
+		IpAddress:  ipAddress,
+	}
+	if err := s.store.CreateSession(session); err != nil {
+		return "", nil, err
+	}
+
+	s.logger.Info("session created", slog.String("userId", userId), slog.String("sessionId", session.ID))
+	return token, session, nil
+}
+
+func (s *SessionService) ResolveSession(token string) (*Session, error) {
+	session := s.store.GetActiveSessionByTokenHash(hashToken(token))
+	if session == nil {
+		return nil, ErrSessionNotFound
+	}
+
+	if now := time.Now().UTC(); now.Sub(session.LastSeenOn) > lastSeenResolution {
+		s.store.UpdateLastSeen(session.ID, now)
+		session.LastSeenOn = now
+	}
+	return session, nil
+}
+
+func (s *SessionService) GetActiveSessions(userId string) []Session {
+	return s.store.GetActiveSessions(userId)
+}
+
+func (s *SessionService) RevokeSession(userId, sessionId string) error {
+	revoked, err := s.store.RevokeSession(userId, sessionId)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrSessionNotFound
+	}
+
+	s.logger.Info("session revoked", slog.String("userId", userId), slog.String("sessionId", sessionId))
+	return nil
+}
+
+func (s *SessionService) RevokeAllSessions(userId string) error {
+	if err := s.store.RevokeUserSessions(userId); err != nil {
+		return err
+	}
+
+	s.logger.Info("all sessions revoked", slog.String("userId", userId))
+	return nil
+}
+
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+func truncate(s string, maxLength int) string {
+	if len(s) > maxLength {
+		return strings.ToValidUTF8(s[:maxLength], "")
+	}
+	return s
+}
@@ -0,0 +1,77 @@
+package session
+
+import (
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type SessionStore struct {
+	db     *gorm.DB
+	logger *slog.Logger
+}
+
+func NewSessionStore(db *gorm.DB, logger *slog.Logger) *SessionStore {
+	return &SessionStore{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (s *SessionStore) CreateSession(session *Session) error {
+	if err := s.db.Create(session).Error; err != nil {
+		s.logger.Error("not able to create a new session", slog.Any("err", err))
+		return err
+	}
+	return nil
+}
+
+func (s *SessionStore) GetActiveSessionByTokenHash(tokenHash string) *Session {
+	var session Session
+	err := s.active(s.db).
+		Select("sessions.*, COALESCE(users.email, '') AS email, COALESCE(users.email_verified, FALSE) AS email_verified").
+		Joins("LEFT JOIN users ON users.id = sessions.user_id").
+		First(&session, "sessions.token_hash = ?", tokenHash).Error
+	if err != nil {
+		return nil
+	}
+	return &session
+}
+
+func (s *SessionStore) GetActiveSessions(userId string) []Session {
+	var sessions []Session
+	err := s.active(s.db).
+		Where("user_id = ?", userId).
+		Order("last_seen_on DESC").
+		Find(&sessions).Error
+	if err != nil {
+		s.logger.Error("not able to get sessions", slog.Any("err", err))
+	}
+	return sessions
+}
+
+func (s *SessionStore) UpdateLastSeen(id string, lastSeenOn time.Time) {
+	err := s.db.Model(&Session{}).Where("id = ?", id).Update("last_seen_on", lastSeenOn).Error
+	if err != nil {
+		s.logger.Error("not able to update session last seen", slog.Any("err", err))
+	}
+}
+
+// Returns whether an active session of the user was revoked
+func (s *SessionStore) RevokeSession(userId, id string) (bool, error) {
+	result := s.active(s.db.Model(&Session{})).
+		Where("id = ? AND user_id = ?", id, userId).
+		Update("revoked_on", time.Now().UTC())
+	return result.RowsAffected > 0, result.Error
+}
+
+func (s *SessionStore) RevokeUserSessions(userId string) error {
+	return s.active(s.db.Model(&Session{})).
+		Where("user_id = ?", userId).
+		Update("revoked_on", time.Now().UTC()).Error
+}
+
+func (s *SessionStore) active(db *gorm.DB) *gorm.DB {
+	return db.Where("sessions.revoked_on IS NULL AND sessions.expires_on > ?", time.Now().UTC())
+}
@@ -0,0 +1,23 @@
+package session
+
+import (
+	"time"
+)
+
+// Server-side login session, the browser only holds an opaque token whose
+// SHA-256 hash is stored here
+type Session struct {
+	internalId uint `gorm:"primaryKey"`
+	ID         string
+	CreatedOn  time.Time
+	LastSeenOn time.Time
+	ExpiresOn  time.Time
+	RevokedOn  *time.Time
+	UserId     string
+	TokenHash  string
+	UserAgent  string
+	IpAddress  string
+	// of the session user
+	Email         string `gorm:"->"`
+	EmailVerified bool   `gorm:"->"`
+}
@@ -0,0 +1,87 @@
+package sport
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+var (
+	ErrNoSport      = errors.New("at least one sport is required")
+	ErrUnknownSport = errors.New("unknown sport")
+)
+
+var otherCategory = SportCategory{Slug: "other", Name: "Other sports"}
+
+type SportService struct {
+	store  *SportStore
+	logger *slog.Logger
+}
+
+func NewSportService(store *SportStore, logger *slog.Logger) *SportService {
+	return &SportService{
+		store:  store,
+		logger: logger,
+	}
+}
+
+func (s *SportService) GetSports() []Sport {
+	return s.store.GetSports()
+}
+
+func (s *SportService) GetCategories() []SportCategory {
+	return s.store.GetCategories()
+}
+
+// Sports without a category are grouped last under "Other sports"
+func (s *SportService) GetCatalogue() []CategorizedSports {
+	sportsByCategory := make(map[string][]Sport)
+	var uncategorized []Sport
+	for _, sport := range s.store.GetSports() {
+		if sport.CategoryId == nil {
+			uncategorized = append(uncategorized, sport)
+			continue
+		}
+		sportsByCategory[*sport.CategoryId] = append(sportsByCategory[*sport.CategoryId], sport)
+	}
+
+	var catalogue []CategorizedSports
+	for _, category := range s.store.GetCategories() {
+		if sports := sportsByCategory[category.ID]; len(sports) > 0 {
+			catalogue = append(catalogue, CategorizedSports{Category: category, Sports: sports})
+		}
+	}
+	if len(uncategorized) > 0 {
+		catalogue = append(catalogue, CategorizedSports{Category: otherCategory, Sports: uncategorized})
+	}
+	return catalogue
+}
+
+// Resolves sport slugs, names or aliases against the catalogue.
+// The order is kept, so the first sport can be used as the primary one.
+func (s *SportService) ResolveSports(values []string) ([]Sport, error) {
+	var sports []Sport
+	seen := make(map[string]bool)
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+
+		sport := s.store.FindSport(value)
+		if sport == nil {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownSport, value)
+		}
+		if seen[sport.ID] {
+			continue
+		}
+		seen[sport.ID] = true
+		sports = append(sports, *sport)
+	}
+
+	if len(sports) == 0 {
+		return nil, ErrNoSport
+	}
+	return sports, nil
+}
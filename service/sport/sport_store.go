@@ -0,0 +1,62 @@
+package sport
+
+import (
+	"log/slog"
+
+	"gorm.io/gorm"
+)
+
+type SportStore struct {
+	db     *gorm.DB
+	logger *slog.Logger
+}
+
+func NewSportStore(db *gorm.DB, logger *slog.Logger) *SportStore {
+	return &SportStore{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Looks up a sport by its slug, display name or one of its aliases
+func (s *SportStore) FindSport(value string) *Sport {
+	var sport Sport
+	if err := s.db.
+		Where("slug = LOWER(?) OR LOWER(name) = LOWER(?)", value, value).
+		Or("id IN (SELECT sport_id FROM sport_aliases WHERE LOWER(alias) = LOWER(?))", value).
+		First(&sport).Error; err != nil {
+		return nil
+	}
+	return &sport
+}
+
+func (s *SportStore) GetSports() []Sport {
+	var sports []Sport
+	if err := s.db.Order("name").Find(&sports).Error; err != nil {
+		s.logger.Error("not able to get sports", slog.Any("err", err))
+		return nil
+	}
+
+	var aliases []SportAlias
+	if err := s.db.Order("alias").Find(&aliases).Error; err != nil {
+		s.logger.Error("not able to get sport aliases", slog.Any("err", err))
+		return sports
+	}
+
+	aliasesBySport := make(map[string][]string)
+	for _, alias := range aliases {
+		aliasesBySport[alias.SportId] = append(aliasesBySport[alias.SportId], alias.Alias)
+	}
+	for i := range sports {
+		sports[i].Aliases = aliasesBySport[sports[i].ID]
+	}
+	return sports
+}
+
+func (s *SportStore) GetCategories() []SportCategory {
+	var categories []SportCategory
+	if err := s.db.Order("name").Find(&categories).Error; err != nil {
+		s.logger.Error("not able to get sport categories", slog.Any("err", err))
+	}
+	return categories
+}
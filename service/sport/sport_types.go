@@ -0,0 +1,32 @@
+package sport
+
+import "time"
+
+type Sport struct {
+	internalId uint `gorm:"primaryKey"`
+	ID         string
+	CreatedOn  time.Time `gorm:"type:timestamp(3) without time zone" json:"created_on"`
+	Slug       string
+	Name       string
+	Icon       string
+	CategoryId *string
+	Aliases    []string `gorm:"-"`
+}
+
+type SportCategory struct {
+	internalId uint `gorm:"primaryKey"`
+	ID         string
+	Slug       string
+	Name       string
+}
+
+type SportAlias struct {
+	Alias   string
+	SportId string
+}
+
+// Sports grouped by their parent category, as rendered in the sport selects
+type CategorizedSports struct {
+	Category SportCategory
+	Sports   []Sport
+}
@@ -2,24 +2,47 @@ package user
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"log/slog"
+	"net/url"
+	"time"
 
-	"firebase.google.com/go/v4/auth"
+	"github.com/google/uuid"
+	"github.com/sportspazz/identity"
+	"github.com/sportspazz/mail"
+)
+
+const (
+	verifyEmailTokenTTL   = 24 * time.Hour
+	resetPasswordTokenTTL = time.Hour
+)
+
+var (
+	ErrInvalidUserToken = errors.New("the link is invalid or has expired")
+	ErrUnknownRole      = errors.New("unknown role")
+	ErrUserNotFound     = errors.New("user not found")
 )
 
 type UserService struct {
-	store          *UserStore
-	firebaseAuthClient *auth.Client
-	logger         *slog.Logger
+	store            *UserStore
+	identityProvider identity.IdentityProvider
+	mailSender       mail.MailSender
+	baseUrl          string
+	logger           *slog.Logger
 }
 
-func NewUserService(store *UserStore, firebaseAuthClient *auth.Client, logger *slog.Logger) *UserService {
+func NewUserService(store *UserStore, identityProvider identity.IdentityProvider, mailSender mail.MailSender, baseUrl string, logger *slog.Logger) *UserService {
 	return &UserService{
-		store:          store,
-		firebaseAuthClient: firebaseAuthClient,
-		logger:         logger,
+		store:            store,
+		identityProvider: identityProvider,
+		mailSender:       mailSender,
+		baseUrl:          baseUrl,
+		logger:           logger,
 	}
 }
 
@@ -28,18 +51,163 @@ func (u *UserService) RegisterUser(email, password string) (*User, error) {
 		return nil, fmt.Errorf("User is already registered")
 	}
 
-	params := (&auth.UserToCreate{}).
-		Email(email).
-		EmailVerified(false).
-		Password(password).
-		Disabled(false)
-	newUser, err := u.firebaseAuthClient.CreateUser(context.Background(), params)
-	
+	userId, err := u.identityProvider.CreateUser(context.Background(), email, password)
+	if errors.Is(err, identity.ErrEmailExists) {
+		return nil, fmt.Errorf("User is already registered")
+	}
 	if err != nil {
-		u.logger.Error("Unable to create user in Firebase", slog.Any("err", err))
+		u.logger.Error("Unable to create user in identity provider", slog.Any("err", err))
 		return nil, errors.New("unable to register due to internal error")
 	}
-	u.logger.Info("New user created in Firebase", slog.Any("user", newUser))
+	u.logger.Info("New user created in identity provider", slog.String("userId", userId))
+
+	user := u.store.CreateUser(userId, email)
+	if err := u.sendWelcomeEmail(user); err != nil {
+		u.logger.Error("Unable to send welcome email", slog.Any("err", err))
+	}
+	if err := u.SendVerificationEmail(user); err != nil {
+		u.logger.Error("Unable to send verification email", slog.Any("err", err))
+	}
+
+	return user, nil
+}
+
+func (u *UserService) GetUserById(id string) *User {
+	return u.store.GetUserById(id)
+}
+
+func (u *UserService) GetRoles(userId string) []Role {
+	return u.store.GetRoles(userId)
+}
+
+func (u *UserService) SendVerificationEmail(user *User) error {
+	token, err := u.createUserToken(user.ID, VerifyEmailPurpose, verifyEmailTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	message, err := mail.VerifyEmailMessage(ctx, user.Email, u.link("/verify-email", token), verifyEmailTokenTTL)
+	if err != nil {
+		return err
+	}
+	return u.mailSender.Send(ctx, message)
+}
+
+func (u *UserService) sendWelcomeEmail(user *User) error {
+	ctx := context.Background()
+	message, err := mail.WelcomeMessage(ctx, user.Email, u.baseUrl)
+	if err != nil {
+		return err
+	}
+	return u.mailSender.Send(ctx, message)
+}
+
+// Tells the submitter of a place that it passed moderation
+func (u *UserService) SendPlaceApprovedEmail(userId, placeName, placePath string) error {
+	user := u.store.GetUserById(userId)
+	if user == nil {
+		return ErrUserNotFound
+	}
+
+	ctx := context.Background()
+	message, err := mail.PlaceApprovedMessage(ctx, user.Email, placeName, u.baseUrl+placePath)
+	if err != nil {
+		return err
+	}
+	return u.mailSender.Send(ctx, message)
+}
+
+func (u *UserService) VerifyEmail(token string) error {
+	userToken := u.store.ConsumeUserToken(hashToken(token), VerifyEmailPurpose)
+	if userToken == nil {
+		return ErrInvalidUserToken
+	}
+
+	verified := true
+	if err := u.identityProvider.UpdateUser(context.Background(), userToken.UserId, identity.UserUpdate{EmailVerified: &verified}); err != nil {
+		u.logger.Error("Unable to mark email verified in identity provider", slog.Any("err", err))
+		return errors.New("unable to verify email due to internal error")
+	}
+	if err := u.store.SetEmailVerified(userToken.UserId); err != nil {
+		u.logger.Error("Unable to mark email verified", slog.Any("err", err))
+		return errors.New("unable to verify email due to internal error")
+	}
+
+	u.logger.Info("Email verified", slog.String("userId", userToken.UserId))
+	return nil
+}
+
+// Silently ignores unknown emails so the form does not reveal who is registered
+func (u *UserService) RequestPasswordReset(email string) error {
+	user := u.store.GetUserByEmail(email)
+	if user == nil {
+		u.logger.Info("Password reset requested for unknown email")
+		return nil
+	}
+
+	token, err := u.createUserToken(user.ID, ResetPasswordPurpose, resetPasswordTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	message, err := mail.ResetPasswordMessage(ctx, user.Email, u.link("/reset-password", token), resetPasswordTokenTTL)
+	if err != nil {
+		return err
+	}
+	return u.mailSender.Send(ctx, message)
+}
+
+// Returns the id of the user whose password was reset
+func (u *UserService) ResetPassword(token, password string) (string, error) {
+	userToken := u.store.ConsumeUserToken(hashToken(token), ResetPasswordPurpose)
+	if userToken == nil {
+		return "", ErrInvalidUserToken
+	}
+
+	// a reset password proves the email address as well
+	verified := true
+	update := identity.UserUpdate{Password: &password, EmailVerified: &verified}
+	if err := u.identityProvider.UpdateUser(context.Background(), userToken.UserId, update); err != nil {
+		u.logger.Error("Unable to update password in identity provider", slog.Any("err", err))
+		return "", errors.New("unable to reset password due to internal error")
+	}
+	if err := u.store.SetEmailVerified(userToken.UserId); err != nil {
+		u.logger.Error("Unable to mark email verified", slog.Any("err", err))
+	}
+
+	u.logger.Info("Password reset", slog.String("userId", userToken.UserId))
+	return userToken.UserId, nil
+}
+
+func (u *UserService) createUserToken(userId string, purpose TokenPurpose, ttl time.Duration) (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(secret)
+
+	now := time.Now().UTC()
+	err := u.store.CreateUserToken(&UserToken{
+		ID:        uuid.New().String(),
+		CreatedOn: now,
+		ExpiresOn: now.Add(ttl),
+		UserId:    userId,
+		Purpose:   purpose,
+		TokenHash: hashToken(token),
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (u *UserService) link(path, token string) string {
+	return u.baseUrl + path + "?token=" + url.QueryEscape(token)
+}
 
-	return u.store.CreateUser(newUser.UserInfo.UID, email), nil
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
 }
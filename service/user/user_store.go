@@ -2,8 +2,10 @@ package user
 
 import (
 	"log/slog"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type UserStore struct {
@@ -26,6 +28,74 @@ func (s *UserStore) GetUserByEmail(email string) *User {
 	return &user
 }
 
+func (s *UserStore) GetUserById(id string) *User {
+	var user User
+	if err := s.db.Where("id = ?", id).First(&user).Error; err != nil {
+		return nil
+	}
+	return &user
+}
+
+func (s *UserStore) SetEmailVerified(id string) error {
+	return s.db.Model(&User{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"email_verified": true,
+			"updated_on":     time.Now().UTC(),
+		}).Error
+}
+
+func (s *UserStore) GetRoles(userId string) []Role {
+	var roles []Role
+	if err := s.db.Model(&UserRole{}).Where("user_id = ?", userId).Order("role").Pluck("role", &roles).Error; err != nil {
+		s.logger.Error("not able to get user roles", slog.Any("err", err))
+	}
+	return roles
+}
+
+func (s *UserStore) GrantRole(userId string, role Role, grantedBy *string) error {
+	userRole := &UserRole{
+		UserId:    userId,
+		Role:      role,
+		CreatedOn: time.Now().UTC(),
+		CreatedBy: grantedBy,
+	}
+	return s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(userRole).Error
+}
+
+// Returns whether the user had the role
+func (s *UserStore) RevokeRole(userId string, role Role) (bool, error) {
+	result := s.db.Where("user_id = ? AND role = ?", userId, role).Delete(&UserRole{})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (s *UserStore) CreateUserToken(token *UserToken) error {
+	if err := s.db.Create(token).Error; err != nil {
+		s.logger.Error("not able to create a user token", slog.Any("err", err))
+		return err
+	}
+	return nil
+}
+
+// Marks an unused, unexpired token as used and returns it, nil when there is no such token
+func (s *UserStore) ConsumeUserToken(tokenHash string, purpose TokenPurpose) *UserToken {
+	now := time.Now().UTC()
+
+	var tokens []UserToken
+	err := s.db.Model(&tokens).
+		Clauses(clause.Returning{}).
+		Where("token_hash = ? AND purpose = ? AND used_on IS NULL AND expires_on > ?", tokenHash, purpose, now).
+		Update("used_on", now).Error
+	if err != nil {
+		s.logger.Error("not able to consume a user token", slog.Any("err", err))
+		return nil
+	}
+	if len(tokens) == 0 {
+		return nil
+	}
+	return &tokens[0]
+}
+
 func (s *UserStore) CreateUser(id, email string) *User {
 	user := NewUser(id, email)
 
@@ -1,15 +1,69 @@
 package user
 
 import (
+	"fmt"
 	"time"
 )
 
 type User struct {
+	internalId    uint `gorm:"primaryKey"`
+	ID            string
+	CreatedOn     time.Time `gorm:"type:datetime(3)"`
+	UpdatedOn     time.Time `gorm:"type:datetime(3)"`
+	Email         string
+	EmailVerified bool
+}
+
+type Role string
+
+const (
+	RoleAdmin     Role = "admin"
+	RoleModerator Role = "moderator"
+	// places submitted by trusted users skip moderation
+	RoleTrusted Role = "trusted"
+)
+
+var Roles = []Role{RoleAdmin, RoleModerator, RoleTrusted}
+
+// Admins are not implied by the other roles, so they are listed explicitly
+var (
+	ModeratorRoles = []Role{RoleAdmin, RoleModerator}
+	TrustedRoles   = []Role{RoleAdmin, RoleModerator, RoleTrusted}
+)
+
+func ParseRole(value string) (Role, error) {
+	for _, role := range Roles {
+		if string(role) == value {
+			return role, nil
+		}
+	}
+	return "", fmt.Errorf("%w %q", ErrUnknownRole, value)
+}
+
+type UserRole struct {
+	UserId    string `gorm:"primaryKey"`
+	Role      Role   `gorm:"primaryKey"`
+	CreatedOn time.Time
+	CreatedBy *string
+}
+
+type TokenPurpose string
+
+const (
+	VerifyEmailPurpose   TokenPurpose = "verify_email"
+	ResetPasswordPurpose TokenPurpose = "reset_password"
+)
+
+// Single-use token sent by email, only its SHA-256 hash is stored
+type UserToken struct {
 	internalId uint `gorm:"primaryKey"`
 	ID         string
-	CreatedOn  time.Time `gorm:"type:datetime(3)"`
-	UpdatedOn  time.Time `gorm:"type:datetime(3)"`
-	Email      string
+	CreatedOn  time.Time
+	ExpiresOn  time.Time
+	UsedOn     *time.Time
+	UserId     string
+	Purpose    TokenPurpose
+	TokenHash  string
 }
 
 func NewUser(id, email string) *User {
@@ -2,8 +2,17 @@ package utils
 
 import (
 	"context"
+	"net"
 	"net/http"
+	"regexp"
+	"slices"
+	"strings"
 	"time"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
 )
 
 type ContextKey string
@@ -11,14 +20,72 @@ type ContextKey string
 const (
 	IdTokenKey      ContextKey = "idToken"
 	RefreshTokenKey ContextKey = "refreshToken"
+	SessionKey      ContextKey = "session"
+	CsrfTokenKey    ContextKey = "csrfToken"
 	LoggerKey       ContextKey = "logger"
 	// For UI
 	UserIdKey  ContextKey = "userId"
 	EmailKey   ContextKey = "email"
 	NameKey    ContextKey = "name"
 	LoginedKey ContextKey = "logined"
+	// Whether the logined user verified their email address
+	EmailVerifiedKey ContextKey = "emailVerified"
+	// Session of the logined user
+	SessionIdKey ContextKey = "sessionId"
+	// Loads the roles of the logined user, a func() []string
+	RolesKey ContextKey = "roles"
 )
 
+func SetSessionCookie(w http.ResponseWriter, token string, expires time.Time) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     string(SessionKey),
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+		Expires:  expires,
+	})
+}
+
+func ClearSessionCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     string(SessionKey),
+		Value:    "",
+		Path:     "/",
+		Expires:  time.Unix(0, 0),
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func SetCsrfCookie(w http.ResponseWriter, token string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     string(CsrfTokenKey),
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+func CsrfToken(ctx context.Context) string {
+	token, _ := ctx.Value(CsrfTokenKey).(string)
+	return token
+}
+
+// IP address of the client without the port
+func ClientIp(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
 func ClearTokenCookies(w http.ResponseWriter) {
 	http.SetCookie(w, &http.Cookie{
 		Name:     string(IdTokenKey),
@@ -47,3 +114,36 @@ func Logined(ctx context.Context) bool {
 	logined := ctx.Value(LoginedKey)
     return logined != nil && logined == true
 }
+
+func EmailVerified(ctx context.Context) bool {
+	verified, _ := ctx.Value(EmailVerifiedKey).(bool)
+	return verified
+}
+
+// Whether the logined user holds any of the roles
+func HasRole[R ~string](ctx context.Context, roles ...R) bool {
+	loadRoles, _ := ctx.Value(RolesKey).(func() []string)
+	if loadRoles == nil {
+		return false
+	}
+
+	userRoles := loadRoles()
+	for _, role := range roles {
+		if slices.Contains(userRoles, string(role)) {
+			return true
+		}
+	}
+	return false
+}
+
+var nonSlugChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Lower-cased, dash separated form of s without accents, usable in urls,
+// e.g. "Montréal" becomes "montreal"
+func Slugify(s string) string {
+	stripAccents := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	if stripped, _, err := transform.String(stripAccents, s); err == nil {
+		s = stripped
+	}
+	return strings.Trim(nonSlugChars.ReplaceAllString(strings.ToLower(s), "-"), "-")
+}